@@ -21,6 +21,7 @@
           mkShell {
             packages = [
               age
+              cosign
               ansible
               ansible-lint
               apacheHttpd
@@ -37,6 +38,7 @@
               opentofu
               oras
               pre-commit
+              restic
               shellcheck
               sops
               temporal-cli
@@ -58,7 +60,7 @@
                   path = ./toolbox;
                   name = "toolbox-src";
                 };
-                vendorHash = "sha256-x5UMiwdqBp0/725PLZifl7m8yOIL25g6uvOW3naWP3g=";
+                vendorHash = "sha256-si0Hs3b8tzN9ivnmKT82TUQdYN3KzdXLg2lOK88sitI=";
               })
             ];
           };
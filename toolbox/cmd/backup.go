@@ -10,21 +10,55 @@ import (
 const (
 	podDetachTimeout = 5 * time.Minute
 	restoreTimeout   = 30 * time.Minute
+	backupTimeout    = 30 * time.Minute
 )
 
 var (
-	backupEnv             string
-	backupSettingsFile    string
-	backupVolumeSelectors []string
+	backupEnv                 string
+	backupSettingsFile        string
+	backupVolumeSelectors     []string
+	backupRestoreAsOf         string
+	backupRestorePrevious     int32
+	backupClone               bool
+	backupRestoreResume       bool
+	backupRestoreAbort        bool
+	backupRestoreState        string
+	backupRestorePVCWorkloads bool
+	backupCloneTarget         cloneFlags
 )
 
+type cloneFlags struct {
+	namespace    string
+	pvc          string
+	storageClass string
+	capacity     string
+}
+
 func init() {
 	backupCmd.PersistentFlags().StringVar(&backupEnv, "env", "", "Environment to manage backups for")
 	backupCmd.PersistentFlags().StringVar(&backupSettingsFile, "settings", "settings.yaml", "Path to settings YAML file")
 	backupCmd.PersistentFlags().StringArrayVar(&backupVolumeSelectors, "volume", nil, "Configured volume to operate on in namespace/pvc format; repeat for multiple volumes")
 
+	backupRestoreCmd.Flags().StringVar(&backupRestoreAsOf, "restore-as-of", "", "Restore the newest snapshot taken at or before this RFC3339 time instead of the latest")
+	backupRestoreCmd.Flags().Int32Var(&backupRestorePrevious, "previous", 0, "Skip this many snapshots, newest first, before selecting one to restore (see backup snapshots)")
+
+	backupRestoreCmd.Flags().BoolVar(&backupClone, "clone", false, "Restore into a new PVC instead of overwriting the volume, without suspending Flux or scaling workloads")
+	backupRestoreCmd.Flags().StringVar(&backupCloneTarget.namespace, "target-namespace", "", "Namespace of the clone (default: namespace of the volume)")
+	backupRestoreCmd.Flags().StringVar(&backupCloneTarget.pvc, "target-pvc", "", "Name of the clone PVC when restoring a single volume (default: <pvc>-clone)")
+	backupRestoreCmd.Flags().StringVar(&backupCloneTarget.storageClass, "storage-class", "", "Storage class of the clone PVC (default: that of the volume's PVC)")
+	backupRestoreCmd.Flags().StringVar(&backupCloneTarget.capacity, "capacity", "", "Capacity of the clone PVC, such as 10Gi (default: that of the volume's PVC)")
+
+	backupRestoreCmd.Flags().BoolVar(&backupRestorePVCWorkloads, "only-pvc-workloads", false, "Only scale down workloads that mount the restored PVCs instead of every workload in their namespaces")
+	backupRestoreCmd.Flags().BoolVar(&backupRestoreResume, "resume", false, "Continue an interrupted restore from its recorded state")
+	backupRestoreCmd.Flags().BoolVar(&backupRestoreAbort, "abort", false, "Roll back an interrupted restore: resume Flux and restore the recorded replica counts")
+	backupRestoreCmd.Flags().StringVar(&backupRestoreState, "state-file", "", "Local file recording restore progress (default: .toolbox/restore-<env>.json)")
+
 	backupCmd.AddCommand(backupSetupCmd)
 	backupCmd.AddCommand(backupRestoreCmd)
+	backupCmd.AddCommand(backupSnapshotsCmd)
+	backupCmd.AddCommand(backupStatusCmd)
+	backupCmd.AddCommand(backupNowCmd)
+	backupCmd.AddCommand(backupVerifyCmd)
 	rootCmd.AddCommand(backupCmd)
 }
 
@@ -47,7 +81,24 @@ var backupRestoreCmd = &cobra.Command{
 	Use:   "restore",
 	Args:  cobra.NoArgs,
 	Short: "Create or patch VolSync ReplicationDestination resources",
-	PreRunE: func(_ *cobra.Command, _ []string) error {
+	PreRunE: func(cmd *cobra.Command, _ []string) error {
+		if backupRestoreResume && backupRestoreAbort {
+			return fmt.Errorf("--resume and --abort are mutually exclusive")
+		}
+		if backupRestoreResume || backupRestoreAbort {
+			for _, name := range []string{"clone", "previous", "restore-as-of", "only-pvc-workloads"} {
+				if cmd.Flags().Changed(name) {
+					return fmt.Errorf("--%s cannot be used with --resume or --abort", name)
+				}
+			}
+		}
+		if !backupClone {
+			for _, name := range []string{"target-namespace", "target-pvc", "storage-class", "capacity"} {
+				if cmd.Flags().Changed(name) {
+					return fmt.Errorf("--%s requires --clone", name)
+				}
+			}
+		}
 		return validateBackupFlags()
 	},
 	RunE: runBackupRestore,
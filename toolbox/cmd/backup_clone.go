@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/khuedoan/cloudlab/toolbox/internal/backup"
+)
+
+// runBackupClone restores volumes into new PVCs next to the originals, which
+// keep serving their workloads.
+func runBackupClone(ctx context.Context, volumes []backup.Volume, options backup.RestoreOptions) error {
+	if backupCloneTarget.pvc != "" && len(volumes) != 1 {
+		return fmt.Errorf("--target-pvc requires selecting exactly one volume with --volume")
+	}
+
+	targets := make([]backup.CloneTarget, 0, len(volumes))
+	var objects []backup.Object
+	for _, volume := range volumes {
+		target, err := cloneTarget(ctx, volume, backup.CloneTarget{
+			Namespace:    cmp.Or(backupCloneTarget.namespace, volume.Namespace),
+			PVC:          cmp.Or(backupCloneTarget.pvc, volume.PVC+"-clone"),
+			StorageClass: backupCloneTarget.storageClass,
+		}, backupCloneTarget.capacity)
+		if err != nil {
+			return err
+		}
+		targets = append(targets, target)
+		objects = append(objects, backup.BuildCloneObjects(volume, target, options)...)
+	}
+
+	if err := applyCloneObjects(ctx, objects); err != nil {
+		return err
+	}
+	for i, volume := range volumes {
+		target := targets[i]
+		name := backup.CloneDestinationName(target)
+		if err := waitForManualSync(ctx, target.Namespace, "replicationdestination/"+name, options.Trigger, restoreTimeout); err != nil {
+			return fmt.Errorf("wait for restore %s/%s: %w", target.Namespace, name, err)
+		}
+		log.Infof("restored %s into %s/%s", volume.Key(), target.Namespace, target.PVC)
+	}
+
+	log.Info("clone restore completed successfully; delete the clone PVCs and ReplicationDestinations when done")
+	return nil
+}
+
+// applyCloneObjects applies the objects of clone restores. Server-side dry
+// runs fail for objects in namespaces that do not exist yet, so the target
+// namespaces are created first.
+func applyCloneObjects(ctx context.Context, objects []backup.Object) error {
+	var namespaces, resources []backup.Object
+	for _, object := range objects {
+		if object.GetObjectKind().GroupVersionKind().Kind == "Namespace" {
+			if !slices.ContainsFunc(namespaces, func(namespace backup.Object) bool { return namespace.GetName() == object.GetName() }) {
+				namespaces = append(namespaces, object)
+			}
+		} else {
+			resources = append(resources, object)
+		}
+	}
+	if len(namespaces) > 0 {
+		if err := applyBackupObjects(ctx, namespaces); err != nil {
+			return err
+		}
+	}
+	return applyBackupObjects(ctx, resources)
+}
+
+// cloneTarget completes the clone target of a volume, taking the storage
+// class and capacity from the volume's PVC when not set.
+func cloneTarget(ctx context.Context, volume backup.Volume, target backup.CloneTarget, capacity string) (backup.CloneTarget, error) {
+	if capacity == "" || target.StorageClass == "" {
+		output, err := runKubectl(ctx, "-n", volume.Namespace, "get", "pvc", volume.PVC, "-o", "jsonpath={.spec.resources.requests.storage} {.spec.storageClassName}")
+		if err != nil {
+			if capacity == "" {
+				return target, fmt.Errorf("get capacity of %s, or pass --capacity: %w (output: %s)", volume.Key(), err, strings.TrimSpace(string(output)))
+			}
+		} else {
+			sourceCapacity, sourceStorageClass, _ := strings.Cut(strings.TrimSpace(string(output)), " ")
+			capacity = cmp.Or(capacity, sourceCapacity)
+			target.StorageClass = cmp.Or(target.StorageClass, sourceStorageClass)
+		}
+	}
+
+	quantity, err := resource.ParseQuantity(capacity)
+	if err != nil {
+		return target, fmt.Errorf("capacity of %s: %w", volume.Key(), err)
+	}
+	target.Capacity = quantity
+
+	if err := backup.ValidateCloneTarget(volume, target); err != nil {
+		return target, err
+	}
+	return target, nil
+}
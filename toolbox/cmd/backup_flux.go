@@ -3,35 +3,38 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"slices"
 	"strings"
 
 	"github.com/khuedoan/cloudlab/toolbox/internal/backup"
 )
 
-type fluxResource struct {
-	kind string
-	name string
-}
-
-func suspendFlux(ctx context.Context, volumes []backup.Volume) ([]fluxResource, error) {
-	var suspended []fluxResource
+// suspendFlux suspends the active Flux resources of the volumes' namespaces
+// that are not in suspended yet, recording each one before moving on so that
+// an interrupted run can be resumed or rolled back.
+func suspendFlux(ctx context.Context, volumes []backup.Volume, suspended []backup.FluxResource, record func(backup.FluxResource) error) error {
 	for _, resource := range targetFluxResources(targetNamespaces(volumes)) {
+		if slices.Contains(suspended, resource) {
+			continue
+		}
 		active, err := fluxResourceActive(ctx, resource)
 		if err != nil {
-			return nil, err
+			return err
 		}
 		if !active {
 			continue
 		}
 		if err := patchFluxResource(ctx, resource, true); err != nil {
-			return nil, err
+			return err
+		}
+		if err := record(resource); err != nil {
+			return err
 		}
-		suspended = append(suspended, resource)
 	}
-	return suspended, nil
+	return nil
 }
 
-func resumeFlux(ctx context.Context, resources []fluxResource) error {
+func resumeFlux(ctx context.Context, resources []backup.FluxResource) error {
 	for _, resource := range resources {
 		if err := patchFluxResource(ctx, resource, false); err != nil {
 			return err
@@ -40,34 +43,34 @@ func resumeFlux(ctx context.Context, resources []fluxResource) error {
 	return nil
 }
 
-func targetFluxResources(namespaces []string) []fluxResource {
-	resources := make([]fluxResource, 0, len(namespaces)*2)
+func targetFluxResources(namespaces []string) []backup.FluxResource {
+	resources := make([]backup.FluxResource, 0, len(namespaces)*2)
 	for _, namespace := range namespaces {
 		resources = append(
 			resources,
-			fluxResource{kind: "helmrelease", name: namespace},
-			fluxResource{kind: "kustomization", name: namespace},
+			backup.FluxResource{Kind: "helmrelease", Name: namespace},
+			backup.FluxResource{Kind: "kustomization", Name: namespace},
 		)
 	}
 	return resources
 }
 
-func fluxResourceActive(ctx context.Context, resource fluxResource) (bool, error) {
-	output, err := runKubectl(ctx, "-n", fluxNamespace, "get", resource.kind, resource.name, "-o", "jsonpath={.spec.suspend}")
+func fluxResourceActive(ctx context.Context, resource backup.FluxResource) (bool, error) {
+	output, err := runKubectl(ctx, "-n", fluxNamespace, "get", resource.Kind, resource.Name, "-o", "jsonpath={.spec.suspend}")
 	if err != nil {
 		if strings.Contains(string(output), "NotFound") {
 			return false, nil
 		}
-		return false, fmt.Errorf("get %s %s: %w", resource.kind, resource.name, err)
+		return false, fmt.Errorf("get %s %s: %w", resource.Kind, resource.Name, err)
 	}
 	return strings.TrimSpace(string(output)) != "true", nil
 }
 
-func patchFluxResource(ctx context.Context, resource fluxResource, suspend bool) error {
+func patchFluxResource(ctx context.Context, resource backup.FluxResource, suspend bool) error {
 	patch := fmt.Sprintf(`{"spec":{"suspend":%t}}`, suspend)
-	output, err := runKubectl(ctx, "-n", fluxNamespace, "patch", resource.kind, resource.name, "--type=merge", "-p", patch)
+	output, err := runKubectl(ctx, "-n", fluxNamespace, "patch", resource.Kind, resource.Name, "--type=merge", "-p", patch)
 	if err != nil {
-		return fmt.Errorf("patch %s %s: %w", resource.kind, resource.name, err)
+		return fmt.Errorf("patch %s %s: %w", resource.Kind, resource.Name, err)
 	}
 	logCommandOutput(output)
 	return nil
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/khuedoan/cloudlab/toolbox/internal/backup"
+)
+
+// runBackupHook runs a pre or post hook command of a volume in the first
+// running pod matching the hook selector. Volumes without the hook are
+// skipped.
+func runBackupHook(ctx context.Context, volume backup.Volume, phase string) error {
+	if volume.Hooks == nil {
+		return nil
+	}
+	command := volume.Hooks.Pre
+	if phase == "post" {
+		command = volume.Hooks.Post
+	}
+	if len(command) == 0 {
+		return nil
+	}
+
+	output, err := runKubectl(ctx, "-n", volume.Namespace, "get", "pod", "-l", volume.Hooks.Selector, "--field-selector=status.phase=Running", "-o", "jsonpath={.items[0].metadata.name}")
+	pod := strings.TrimSpace(string(output))
+	if err != nil || pod == "" {
+		return fmt.Errorf("%s hook: no running pod matches %s in %s: %v (output: %s)", phase, volume.Hooks.Selector, volume.Namespace, err, pod)
+	}
+
+	args := []string{"-n", volume.Namespace, "exec", pod}
+	if volume.Hooks.Container != "" {
+		args = append(args, "-c", volume.Hooks.Container)
+	}
+	log.Infof("running %s hook for %s in pod %s", phase, volume.Key(), pod)
+	output, err = runKubectl(ctx, append(append(args, "--"), command...)...)
+	if err != nil {
+		return fmt.Errorf("%s hook in pod %s/%s: %w (output: %s)", phase, volume.Namespace, pod, err, strings.TrimSpace(string(output)))
+	}
+	logCommandOutput(output)
+	return nil
+}
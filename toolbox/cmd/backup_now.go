@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/spf13/cobra"
+
+	"github.com/khuedoan/cloudlab/toolbox/internal/backup"
+)
+
+var backupNowCmd = &cobra.Command{
+	Use:   "now",
+	Args:  cobra.NoArgs,
+	Short: "Back up configured volumes immediately and wait for the backups to finish",
+	PreRunE: func(_ *cobra.Command, _ []string) error {
+		return validateBackupFlags()
+	},
+	RunE: runBackupNow,
+}
+
+func runBackupNow(cmd *cobra.Command, _ []string) error {
+	backupTrigger := "backup-" + time.Now().UTC().Format("20060102T150405.000000000Z")
+	log.Infof("using backup trigger %s", backupTrigger)
+
+	volumes, err := prepareBackupRun(cmd.Context(), false)
+	if err != nil {
+		return err
+	}
+
+	// Start every backup before waiting so that they run concurrently. Post
+	// hooks run once a backup is done, or right away if it could not start.
+	var triggered []backup.Volume
+	failed := map[string]bool{}
+	fail := func(volume backup.Volume, format string, err error) {
+		log.Errorf(format, volume.Key(), err)
+		failed[volume.Key()] = true
+	}
+	for _, volume := range volumes {
+		if err := runBackupHook(cmd.Context(), volume, "pre"); err != nil {
+			fail(volume, "backup failed for %s: %v", err)
+		} else if err := patchBackupTrigger(cmd.Context(), volume, fmt.Sprintf("%q", backupTrigger)); err != nil {
+			fail(volume, "backup failed for %s: %v", err)
+		} else {
+			triggered = append(triggered, volume)
+			continue
+		}
+		if err := runBackupHook(cmd.Context(), volume, "post"); err != nil {
+			fail(volume, "post hook failed for %s: %v", err)
+		}
+	}
+
+	for _, volume := range triggered {
+		resource := "replicationsource/" + backup.SourceName(volume)
+		if err := waitForManualSync(cmd.Context(), volume.Namespace, resource, backupTrigger, backupTimeout); err != nil {
+			fail(volume, "backup failed for %s: %v", err)
+		} else {
+			log.Infof("backup completed for %s", volume.Key())
+		}
+
+		// A manual trigger takes precedence over the schedule, so remove it
+		// to resume scheduled backups.
+		if err := patchBackupTrigger(cmd.Context(), volume, "null"); err != nil {
+			fail(volume, "resume scheduled backups for %s: %v", err)
+		}
+		if err := runBackupHook(cmd.Context(), volume, "post"); err != nil {
+			fail(volume, "post hook failed for %s: %v", err)
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("%d of %d backup(s) failed", len(failed), len(volumes))
+	}
+	log.Info("all backups completed successfully")
+	return nil
+}
+
+// patchBackupTrigger sets the manual trigger of a volume's ReplicationSource
+// to a JSON value, where null removes it.
+func patchBackupTrigger(ctx context.Context, volume backup.Volume, trigger string) error {
+	name := backup.SourceName(volume)
+	patch := fmt.Sprintf(`{"spec":{"trigger":{"manual":%s}}}`, trigger)
+	output, err := runKubectl(ctx, "-n", volume.Namespace, "patch", "replicationsource", name, "--type=merge", "-p", patch)
+	if err != nil {
+		return fmt.Errorf("patch ReplicationSource %s/%s: %w (output: %s)", volume.Namespace, name, err, strings.TrimSpace(string(output)))
+	}
+	logCommandOutput(output)
+	return nil
+}
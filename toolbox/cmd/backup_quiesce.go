@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/khuedoan/cloudlab/toolbox/internal/backup"
+)
+
+const cnpgClusters = "clusters.postgresql.cnpg.io"
+
+// suspendCronJobs suspends the active CronJobs of the volumes' namespaces
+// that are not in suspended yet, recording each one before moving on.
+func suspendCronJobs(ctx context.Context, volumes []backup.Volume, pvcOnly bool, suspended []backup.CronJob, record func(backup.CronJob) error) error {
+	for _, namespace := range targetNamespaces(volumes) {
+		output, err := runKubectl(ctx, "-n", namespace, "get", "cronjob", "-o", backup.CronJobsJSONPath)
+		if err != nil {
+			return fmt.Errorf("get CronJobs in %s: %w (output: %s)", namespace, err, strings.TrimSpace(string(output)))
+		}
+		for _, cronJob := range backup.ActiveCronJobs(backup.ParseCronJobs(namespace, output), volumes, pvcOnly) {
+			if slices.ContainsFunc(suspended, func(other backup.CronJob) bool { return other.Namespace == namespace && other.Name == cronJob.Name }) {
+				continue
+			}
+			if err := patchCronJob(ctx, cronJob, true); err != nil {
+				return err
+			}
+			if err := record(cronJob); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func resumeCronJobs(ctx context.Context, cronJobs []backup.CronJob) error {
+	for _, cronJob := range cronJobs {
+		if err := patchCronJob(ctx, cronJob, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func patchCronJob(ctx context.Context, cronJob backup.CronJob, suspend bool) error {
+	patch := fmt.Sprintf(`{"spec":{"suspend":%t}}`, suspend)
+	output, err := runKubectl(ctx, "-n", cronJob.Namespace, "patch", "cronjob", cronJob.Name, "--type=merge", "-p", patch)
+	if err != nil {
+		return fmt.Errorf("patch CronJob %s/%s: %w (output: %s)", cronJob.Namespace, cronJob.Name, err, strings.TrimSpace(string(output)))
+	}
+	logCommandOutput(output)
+	return nil
+}
+
+// hibernateClusters hibernates the CloudNativePG clusters of the volumes'
+// namespaces that are not in hibernated yet, recording each one before moving
+// on.
+func hibernateClusters(ctx context.Context, volumes []backup.Volume, pvcOnly bool, hibernated []backup.Cluster, record func(backup.Cluster) error) error {
+	for _, namespace := range targetNamespaces(volumes) {
+		output, err := runKubectl(ctx, "-n", namespace, "get", cnpgClusters, "-o", backup.ClustersJSONPath)
+		if err != nil {
+			if strings.Contains(string(output), "doesn't have a resource type") {
+				return nil
+			}
+			return fmt.Errorf("get CloudNativePG clusters in %s: %w (output: %s)", namespace, err, strings.TrimSpace(string(output)))
+		}
+		for _, cluster := range backup.AwakeClusters(backup.ParseClusters(namespace, output), volumes, pvcOnly) {
+			if slices.ContainsFunc(hibernated, func(other backup.Cluster) bool { return other.Namespace == namespace && other.Name == cluster.Name }) {
+				continue
+			}
+			if err := annotateCluster(ctx, cluster, backup.CNPGHibernationAnnotation+"=on"); err != nil {
+				return err
+			}
+			if err := record(cluster); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// wakeClusters sets the hibernation annotation of clusters back to the value
+// it had before the restore.
+func wakeClusters(ctx context.Context, clusters []backup.Cluster) error {
+	for _, cluster := range clusters {
+		annotation := backup.CNPGHibernationAnnotation + "-"
+		if cluster.Hibernation != "" {
+			annotation = backup.CNPGHibernationAnnotation + "=" + cluster.Hibernation
+		}
+		if err := annotateCluster(ctx, cluster, annotation); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func annotateCluster(ctx context.Context, cluster backup.Cluster, annotation string) error {
+	output, err := runKubectl(ctx, "-n", cluster.Namespace, "annotate", cnpgClusters, cluster.Name, annotation, "--overwrite")
+	if err != nil {
+		return fmt.Errorf("annotate CloudNativePG cluster %s/%s: %w (output: %s)", cluster.Namespace, cluster.Name, err, strings.TrimSpace(string(output)))
+	}
+	logCommandOutput(output)
+	return nil
+}
+
+// warnUnmanagedPods warns about pods mounting the volumes that restore cannot
+// stop, which keep the PVCs attached until they are stopped by hand.
+func warnUnmanagedPods(ctx context.Context, volumes []backup.Volume) error {
+	for _, namespace := range targetNamespaces(volumes) {
+		pods, err := listPods(ctx, namespace)
+		if err != nil {
+			return err
+		}
+		for _, pod := range backup.UnmanagedPods(pods, volumes) {
+			owner := "a bare pod"
+			if len(pod.Owners) > 0 {
+				owner = "owned by a " + strings.Join(pod.Owners, ", ")
+			}
+			log.Warnf("pod %s/%s mounts %s and is %s, which restore cannot stop; delete it so the restore can proceed", pod.Namespace, pod.Name, strings.Join(pod.Claims, ", "), owner)
+		}
+	}
+	return nil
+}
+
+func listPods(ctx context.Context, namespace string) ([]backup.Pod, error) {
+	output, err := runKubectl(ctx, "-n", namespace, "get", "pod", "-o", backup.PodsJSONPath)
+	if err != nil {
+		return nil, fmt.Errorf("get pods in %s: %w (output: %s)", namespace, err, strings.TrimSpace(string(output)))
+	}
+	return backup.ParsePods(namespace, output), nil
+}
@@ -0,0 +1,298 @@
+package cmd
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/spf13/cobra"
+
+	"github.com/khuedoan/cloudlab/toolbox/internal/backup"
+)
+
+func runBackupRestore(cmd *cobra.Command, _ []string) error {
+	statePath := cmp.Or(backupRestoreState, backup.RestoreStatePath(backupEnv))
+	if backupRestoreResume || backupRestoreAbort {
+		return continueRestore(cmd.Context(), statePath)
+	}
+
+	options, err := restoreOptions(cmd)
+	if err != nil {
+		return err
+	}
+	log.Infof("using restore trigger %s", options.Trigger)
+
+	volumes, err := prepareBackupRun(cmd.Context(), false)
+	if err != nil {
+		return err
+	}
+	if backupClone {
+		return runBackupClone(cmd.Context(), volumes, options)
+	}
+
+	existing, err := loadRestoreState(cmd.Context(), statePath, volumes)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return fmt.Errorf("a restore of %s is already in progress after step %q; run backup restore --resume or --abort first", strings.Join(existing.Volumes, ", "), existing.Step)
+	}
+
+	if err := ensurePVCs(cmd.Context(), volumes, "destination"); err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(volumes))
+	for _, volume := range volumes {
+		keys = append(keys, volume.Key())
+	}
+	run := &restoreRun{
+		statePath: statePath,
+		state: &backup.RestoreState{
+			Env:              backupEnv,
+			Volumes:          keys,
+			Options:          options,
+			PVCWorkloadsOnly: backupRestorePVCWorkloads,
+		},
+		volumes: volumes,
+	}
+	if err := run.save(cmd.Context()); err != nil {
+		return err
+	}
+	return run.run(cmd.Context())
+}
+
+// continueRestore resumes or aborts the restore recorded in the state file,
+// or in the namespace annotations if the file is gone.
+func continueRestore(ctx context.Context, statePath string) error {
+	candidates, err := loadBackupVolumes(backupVolumeSelectors)
+	if err != nil {
+		return err
+	}
+	state, err := loadRestoreState(ctx, statePath, candidates)
+	if err != nil {
+		return err
+	}
+	if state == nil {
+		return fmt.Errorf("no restore in progress for %s", backupEnv)
+	}
+	if state.Env != backupEnv {
+		return fmt.Errorf("the restore in progress is for %s, not %s", state.Env, backupEnv)
+	}
+
+	volumes, err := loadBackupVolumes(state.Volumes)
+	if err != nil {
+		return err
+	}
+	run := &restoreRun{statePath: statePath, state: state, volumes: volumes}
+	if backupRestoreAbort {
+		return run.abort(ctx)
+	}
+	log.Infof("resuming restore of %s after step %q", strings.Join(state.Volumes, ", "), state.Step)
+	return run.run(ctx)
+}
+
+func restoreOptions(cmd *cobra.Command) (backup.RestoreOptions, error) {
+	options := backup.RestoreOptions{
+		Trigger: "restore-" + time.Now().UTC().Format("20060102T150405.000000000Z"),
+	}
+	if backupRestoreAsOf != "" {
+		restoreAsOf, err := time.Parse(time.RFC3339, backupRestoreAsOf)
+		if err != nil {
+			return options, fmt.Errorf("--restore-as-of must be an RFC3339 time such as 2026-01-02T15:04:05Z: %w", err)
+		}
+		options.RestoreAsOf = &restoreAsOf
+		log.Infof("restoring the newest snapshot as of %s", restoreAsOf.UTC().Format(time.RFC3339))
+	}
+	if cmd.Flags().Changed("previous") {
+		if backupRestorePrevious < 0 {
+			return options, fmt.Errorf("--previous must be >= 0")
+		}
+		options.Previous = &backupRestorePrevious
+		log.Infof("skipping %d snapshot(s) before the one to restore", backupRestorePrevious)
+	}
+	return options, nil
+}
+
+// restoreRun is an in-place restore whose progress is recorded after every
+// step, so that it can be resumed or rolled back after a failure.
+type restoreRun struct {
+	statePath string
+	state     *backup.RestoreState
+	volumes   []backup.Volume
+}
+
+func (r *restoreRun) run(ctx context.Context) error {
+	for _, step := range r.state.RemainingSteps() {
+		log.Infof("restore step %s", step)
+		if err := r.runStep(ctx, step); err != nil {
+			return fmt.Errorf("restore step %s: %w; Flux and workloads are left as they are for inspection; run backup restore --env %s --resume to continue or --abort to roll back", step, err, r.state.Env)
+		}
+		r.state.Step = step
+		if err := r.save(ctx); err != nil {
+			return err
+		}
+	}
+	if err := r.clear(ctx); err != nil {
+		return err
+	}
+
+	log.Info("restore completed successfully")
+	return nil
+}
+
+func (r *restoreRun) runStep(ctx context.Context, step string) error {
+	switch step {
+	case backup.StepSuspendFlux:
+		return suspendFlux(ctx, r.volumes, r.state.Suspended, func(resource backup.FluxResource) error {
+			r.state.Suspended = append(r.state.Suspended, resource)
+			return r.save(ctx)
+		})
+	case backup.StepScaleDown:
+		if r.state.Workloads == nil {
+			workloads, err := recordWorkloads(ctx, r.volumes, r.state.PVCWorkloadsOnly)
+			if err != nil {
+				return err
+			}
+			r.state.Workloads = workloads
+			if err := r.save(ctx); err != nil {
+				return err
+			}
+		}
+		if err := suspendCronJobs(ctx, r.volumes, r.state.PVCWorkloadsOnly, r.state.CronJobs, func(cronJob backup.CronJob) error {
+			r.state.CronJobs = append(r.state.CronJobs, cronJob)
+			return r.save(ctx)
+		}); err != nil {
+			return err
+		}
+		if err := hibernateClusters(ctx, r.volumes, r.state.PVCWorkloadsOnly, r.state.Clusters, func(cluster backup.Cluster) error {
+			r.state.Clusters = append(r.state.Clusters, cluster)
+			return r.save(ctx)
+		}); err != nil {
+			return err
+		}
+		if err := warnUnmanagedPods(ctx, r.volumes); err != nil {
+			return err
+		}
+		return scaleWorkloadsDown(ctx, r.state.Workloads)
+	case backup.StepWaitDetached:
+		return waitForPodsDetached(ctx, r.volumes, r.state.PVCWorkloadsOnly)
+	case backup.StepApply:
+		return applyBackupObjects(ctx, backup.BuildRestoreObjects(r.volumes, r.state.Options))
+	case backup.StepWaitRestore:
+		return waitForRestores(ctx, r.volumes, r.state.Options.Trigger)
+	case backup.StepScaleUp:
+		return r.unquiesce(ctx)
+	case backup.StepResumeFlux:
+		return resumeFlux(ctx, r.state.Suspended)
+	}
+	return fmt.Errorf("unknown restore step %q", step)
+}
+
+// abort undoes what the restore changed. Data that a restore mover already
+// wrote to the PVCs cannot be rolled back.
+func (r *restoreRun) abort(ctx context.Context) error {
+	if r.state.Reached(backup.StepWaitDetached) {
+		for _, volume := range r.volumes {
+			name := backup.DestinationName(volume)
+			output, err := runKubectl(ctx, "-n", volume.Namespace, "delete", "replicationdestination", name, "--ignore-not-found")
+			if err != nil {
+				return fmt.Errorf("delete ReplicationDestination %s/%s: %w (output: %s)", volume.Namespace, name, err, strings.TrimSpace(string(output)))
+			}
+			logCommandOutput(output)
+		}
+	}
+	if err := r.unquiesce(ctx); err != nil {
+		return err
+	}
+	if err := resumeFlux(ctx, r.state.Suspended); err != nil {
+		return err
+	}
+	if err := r.clear(ctx); err != nil {
+		return err
+	}
+
+	log.Info("restore aborted; Flux and workloads are back to their state before the restore")
+	return nil
+}
+
+// unquiesce wakes the hibernated clusters, scales workloads back up and
+// resumes the suspended CronJobs.
+func (r *restoreRun) unquiesce(ctx context.Context) error {
+	if err := wakeClusters(ctx, r.state.Clusters); err != nil {
+		return err
+	}
+	if err := restoreWorkloads(ctx, r.state.Workloads); err != nil {
+		return err
+	}
+	return resumeCronJobs(ctx, r.state.CronJobs)
+}
+
+// save writes the state to the local state file and to the annotation of
+// every restored namespace.
+func (r *restoreRun) save(ctx context.Context) error {
+	if err := r.state.Save(r.statePath); err != nil {
+		return fmt.Errorf("save restore state: %w", err)
+	}
+	data, err := json.Marshal(r.state)
+	if err != nil {
+		return err
+	}
+	return annotateRestoreNamespaces(ctx, r.volumes, backup.RestoreStateAnnotation+"="+string(data))
+}
+
+func (r *restoreRun) clear(ctx context.Context) error {
+	if err := os.Remove(r.statePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("remove restore state: %w", err)
+	}
+	return annotateRestoreNamespaces(ctx, r.volumes, backup.RestoreStateAnnotation+"-")
+}
+
+func annotateRestoreNamespaces(ctx context.Context, volumes []backup.Volume, annotation string) error {
+	for _, namespace := range targetNamespaces(volumes) {
+		output, err := runKubectl(ctx, "annotate", "namespace", namespace, annotation, "--overwrite")
+		if err != nil {
+			return fmt.Errorf("annotate namespace %s with restore state: %w (output: %s)", namespace, err, strings.TrimSpace(string(output)))
+		}
+	}
+	return nil
+}
+
+// loadRestoreState returns the restore in progress from the local state file,
+// or from the annotation of one of the volumes' namespaces.
+func loadRestoreState(ctx context.Context, statePath string, volumes []backup.Volume) (*backup.RestoreState, error) {
+	state, err := backup.LoadRestoreState(statePath)
+	if err != nil || state != nil {
+		return state, err
+	}
+
+	jsonpath := "jsonpath={.metadata.annotations." + strings.ReplaceAll(backup.RestoreStateAnnotation, ".", `\.`) + "}"
+	for _, namespace := range targetNamespaces(volumes) {
+		output, err := runKubectl(ctx, "get", "namespace", namespace, "-o", jsonpath)
+		if err != nil {
+			return nil, fmt.Errorf("get restore state of namespace %s: %w (output: %s)", namespace, err, strings.TrimSpace(string(output)))
+		}
+		if data := strings.TrimSpace(string(output)); data != "" {
+			log.Infof("using restore state from namespace %s", namespace)
+			return backup.ParseRestoreState([]byte(data))
+		}
+	}
+	return nil, nil
+}
+
+func waitForRestores(ctx context.Context, volumes []backup.Volume, restoreTrigger string) error {
+	for _, volume := range volumes {
+		name := backup.DestinationName(volume)
+		if err := waitForManualSync(ctx, volume.Namespace, "replicationdestination/"+name, restoreTrigger, restoreTimeout); err != nil {
+			return fmt.Errorf("wait for restore %s/%s: %w", volume.Namespace, name, err)
+		}
+		log.Infof("restore completed for %s/%s", volume.Namespace, name)
+	}
+	return nil
+}
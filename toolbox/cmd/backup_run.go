@@ -11,7 +11,24 @@ import (
 )
 
 func prepareBackupRun(ctx context.Context, requireSourcePVC bool) ([]backup.Volume, error) {
-	config, err := backup.LoadConfig(backupSettingsFile)
+	volumes, err := loadBackupVolumes(backupVolumeSelectors)
+	if err != nil {
+		return nil, err
+	}
+
+	if requireSourcePVC {
+		if err := ensurePVCs(ctx, volumes, "source"); err != nil {
+			return nil, err
+		}
+	}
+
+	return volumes, nil
+}
+
+// loadBackupVolumes returns the configured volumes matching selectors, or
+// every volume without selectors.
+func loadBackupVolumes(selectors []string) ([]backup.Volume, error) {
+	config, err := backup.LoadConfig(backupSettingsFile, backupEnv)
 	if err != nil {
 		return nil, fmt.Errorf("load settings file: %w", err)
 	}
@@ -20,17 +37,10 @@ func prepareBackupRun(ctx context.Context, requireSourcePVC bool) ([]backup.Volu
 	if err != nil {
 		return nil, fmt.Errorf("validate backup inventory: %w", err)
 	}
-	volumes, err = backup.FilterVolumes(volumes, backupVolumeSelectors)
+	volumes, err = backup.FilterVolumes(volumes, selectors)
 	if err != nil {
 		return nil, fmt.Errorf("select backup volumes: %w", err)
 	}
-
-	if requireSourcePVC {
-		if err := ensurePVCs(ctx, volumes, "source"); err != nil {
-			return nil, err
-		}
-	}
-
 	return volumes, nil
 }
 
@@ -5,39 +5,112 @@ import (
 	"fmt"
 	"maps"
 	"slices"
+	"strings"
+
+	"github.com/charmbracelet/log"
 
 	"github.com/khuedoan/cloudlab/toolbox/internal/backup"
 )
 
-func scaleRestoreNamespaces(ctx context.Context, volumes []backup.Volume, replicas int) error {
+func targetNamespaces(volumes []backup.Volume) []string {
+	namespaces := map[string]bool{}
+	for _, volume := range volumes {
+		namespaces[volume.Namespace] = true
+	}
+	return slices.Sorted(maps.Keys(namespaces))
+}
+
+// recordWorkloads returns the Deployments and StatefulSets in the volumes'
+// namespaces, or only those mounting the volumes' PVCs, with their current
+// replica counts and autoscaler bounds.
+func recordWorkloads(ctx context.Context, volumes []backup.Volume, pvcWorkloadsOnly bool) ([]backup.Workload, error) {
+	workloads := []backup.Workload{}
 	for _, namespace := range targetNamespaces(volumes) {
-		if err := scaleNamespaceWorkloads(ctx, namespace, replicas); err != nil {
+		output, err := runKubectl(ctx, "-n", namespace, "get", "deployment,statefulset", "-o", backup.WorkloadsJSONPath)
+		if err != nil {
+			return nil, fmt.Errorf("get workloads in %s: %w (output: %s)", namespace, err, strings.TrimSpace(string(output)))
+		}
+		namespaceWorkloads, err := backup.ParseWorkloads(namespace, output)
+		if err != nil {
+			return nil, err
+		}
+
+		output, err = runKubectl(ctx, "-n", namespace, "get", "horizontalpodautoscaler", "-o", backup.AutoscalersJSONPath)
+		if err != nil {
+			return nil, fmt.Errorf("get HorizontalPodAutoscalers in %s: %w (output: %s)", namespace, err, strings.TrimSpace(string(output)))
+		}
+		if err := backup.AttachAutoscalers(namespaceWorkloads, output); err != nil {
+			return nil, fmt.Errorf("%s: %w", namespace, err)
+		}
+		workloads = append(workloads, namespaceWorkloads...)
+	}
+
+	if pvcWorkloadsOnly {
+		workloads = backup.MountingWorkloads(workloads, volumes)
+	}
+	for _, workload := range workloads {
+		log.Infof("recorded %s %s/%s with %d replica(s)", workload.Kind, workload.Namespace, workload.Name, workload.Replicas)
+	}
+	return workloads, nil
+}
+
+func scaleWorkloadsDown(ctx context.Context, workloads []backup.Workload) error {
+	// HorizontalPodAutoscalers leave workloads at 0 replicas alone, so they
+	// do not need to be changed.
+	for _, workload := range workloads {
+		if err := scaleWorkload(ctx, workload, 0); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func scaleNamespaceWorkloads(ctx context.Context, namespace string, replicas int) error {
-	output, err := runKubectl(ctx, "-n", namespace, "scale", "deployment,statefulset", "--all", fmt.Sprintf("--replicas=%d", replicas))
-	if err != nil {
-		return fmt.Errorf("scale workloads in %s to %d replica(s): %w", namespace, replicas, err)
+// restoreWorkloads scales workloads back to their recorded replica counts and
+// resets their autoscalers to the recorded bounds.
+func restoreWorkloads(ctx context.Context, workloads []backup.Workload) error {
+	for _, workload := range workloads {
+		if err := scaleWorkload(ctx, workload, workload.Replicas); err != nil {
+			return err
+		}
+		if workload.HPA == nil {
+			continue
+		}
+		patch := fmt.Sprintf(`{"spec":{"minReplicas":%d,"maxReplicas":%d}}`, workload.HPA.MinReplicas, workload.HPA.MaxReplicas)
+		output, err := runKubectl(ctx, "-n", workload.Namespace, "patch", "horizontalpodautoscaler", workload.HPA.Name, "--type=merge", "-p", patch)
+		if err != nil {
+			return fmt.Errorf("patch HorizontalPodAutoscaler %s/%s: %w (output: %s)", workload.Namespace, workload.HPA.Name, err, strings.TrimSpace(string(output)))
+		}
+		logCommandOutput(output)
 	}
-	logCommandOutput(output)
 	return nil
 }
 
-func targetNamespaces(volumes []backup.Volume) []string {
-	namespaces := map[string]bool{}
-	for _, volume := range volumes {
-		namespaces[volume.Namespace] = true
+func scaleWorkload(ctx context.Context, workload backup.Workload, replicas int32) error {
+	output, err := runKubectl(ctx, "-n", workload.Namespace, "scale", workload.Kind+"/"+workload.Name, fmt.Sprintf("--replicas=%d", replicas))
+	if err != nil {
+		return fmt.Errorf("scale %s %s/%s to %d replica(s): %w (output: %s)", workload.Kind, workload.Namespace, workload.Name, replicas, err, strings.TrimSpace(string(output)))
 	}
-	return slices.Sorted(maps.Keys(namespaces))
+	logCommandOutput(output)
+	return nil
 }
 
-func waitForPodsDetached(ctx context.Context, volumes []backup.Volume) error {
+// waitForPodsDetached waits until no pod in the volumes' namespaces is left,
+// or with pvcWorkloadsOnly, until no pod mounts the volumes' PVCs.
+func waitForPodsDetached(ctx context.Context, volumes []backup.Volume, pvcWorkloadsOnly bool) error {
 	for _, namespace := range targetNamespaces(volumes) {
-		output, err := runKubectl(ctx, "-n", namespace, "wait", "--for=delete", "pod", "--all", "--timeout="+podDetachTimeout.String())
+		pods := []string{"pod", "--all"}
+		if pvcWorkloadsOnly {
+			mounting, err := podsMountingVolumes(ctx, namespace, volumes)
+			if err != nil {
+				return err
+			}
+			if len(mounting) == 0 {
+				continue
+			}
+			pods = mounting
+		}
+
+		output, err := runKubectl(ctx, append([]string{"-n", namespace, "wait", "--for=delete", "--timeout=" + podDetachTimeout.String()}, pods...)...)
 		if err != nil {
 			return fmt.Errorf("wait for pods in %s to stop: %w", namespace, err)
 		}
@@ -45,3 +118,18 @@ func waitForPodsDetached(ctx context.Context, volumes []backup.Volume) error {
 	}
 	return nil
 }
+
+// podsMountingVolumes returns the pods in namespace that mount one of the
+// volumes' PVCs, as pod/<name> resources.
+func podsMountingVolumes(ctx context.Context, namespace string, volumes []backup.Volume) ([]string, error) {
+	pods, err := listPods(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, pod := range backup.MountingPods(pods, volumes) {
+		names = append(names, "pod/"+pod.Name)
+	}
+	return names, nil
+}
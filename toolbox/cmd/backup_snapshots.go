@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/khuedoan/cloudlab/toolbox/internal/backup"
+)
+
+var backupSnapshotsCmd = &cobra.Command{
+	Use:   "snapshots",
+	Args:  cobra.NoArgs,
+	Short: "List the restic snapshots of configured volumes to pick one to restore",
+	PreRunE: func(_ *cobra.Command, _ []string) error {
+		if err := validateBackupFlags(); err != nil {
+			return err
+		}
+		return requireExecutables("restic")
+	},
+	RunE: runBackupSnapshots,
+}
+
+func runBackupSnapshots(cmd *cobra.Command, _ []string) error {
+	volumes, err := prepareBackupRun(cmd.Context(), false)
+	if err != nil {
+		return err
+	}
+
+	credentials, err := readBackupCredentials(cmd.Context())
+	if err != nil {
+		return err
+	}
+
+	table := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "VOLUME\tPREVIOUS\tID\tTIME\tHOST")
+	for _, volume := range volumes {
+		snapshots, err := backup.ListSnapshots(cmd.Context(), credentials, volume)
+		if err != nil {
+			return err
+		}
+		for i, snapshot := range snapshots {
+			fmt.Fprintf(table, "%s\t%d\t%s\t%s\t%s\n", volume.Key(), i, snapshot.ID, snapshot.Time.UTC().Format(time.RFC3339), snapshot.Hostname)
+		}
+	}
+	return table.Flush()
+}
+
+// readBackupCredentials reads the restic password and S3 settings that the
+// repository secrets reference from Vault.
+func readBackupCredentials(ctx context.Context) (backup.Credentials, error) {
+	vault, stopVault, err := connectVault(ctx)
+	if err != nil {
+		return backup.Credentials{}, fmt.Errorf("connect to Vault: %w", err)
+	}
+	defer stopVault()
+
+	reader := vaultSecretReader{client: vault}
+	values := map[string]string{}
+	for path, keys := range map[string][]string{
+		"secret/backup/restic": {"password"},
+		"secret/backup/s3":     {"endpoint", "bucket", "access_key_id", "secret_access_key"},
+	} {
+		data, err := reader.ReadSecret(ctx, path)
+		if err != nil {
+			return backup.Credentials{}, fmt.Errorf("read %s: %w", path, err)
+		}
+		for _, key := range keys {
+			value, ok := data[key].(string)
+			if !ok || value == "" {
+				return backup.Credentials{}, fmt.Errorf("%s#%s is not set", path, key)
+			}
+			values[key] = value
+		}
+	}
+
+	return backup.Credentials{
+		Password:        values["password"],
+		Endpoint:        strings.TrimSuffix(values["endpoint"], "/"),
+		Bucket:          values["bucket"],
+		AccessKeyID:     values["access_key_id"],
+		SecretAccessKey: values["secret_access_key"],
+	}, nil
+}
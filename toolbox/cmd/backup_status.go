@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	volsyncv1alpha1 "github.com/backube/volsync/api/v1alpha1"
+	"github.com/charmbracelet/log"
+	"github.com/spf13/cobra"
+
+	"github.com/khuedoan/cloudlab/toolbox/internal/backup"
+)
+
+var backupMaxAge time.Duration
+
+var backupStatusCmd = &cobra.Command{
+	Use:     "status",
+	Aliases: []string{"list"},
+	Args:    cobra.NoArgs,
+	Short:   "Show backup health of configured volumes and fail if any is stale",
+	PreRunE: func(_ *cobra.Command, _ []string) error {
+		if backupMaxAge <= 0 {
+			return fmt.Errorf("--max-age must be positive")
+		}
+		return validateBackupFlags()
+	},
+	RunE: runBackupStatus,
+}
+
+func init() {
+	backupStatusCmd.Flags().DurationVar(&backupMaxAge, "max-age", 24*time.Hour, "Flag volumes whose last successful backup is older than this")
+}
+
+func runBackupStatus(cmd *cobra.Command, _ []string) error {
+	volumes, err := prepareBackupRun(cmd.Context(), false)
+	if err != nil {
+		return err
+	}
+
+	sources, err := getReplicationSources(cmd.Context())
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	table := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "VOLUME\tLAST SYNC\tDURATION\tNEXT SYNC\tRESULT\tCONDITIONS\tHEALTH")
+	var unhealthy []backup.Status
+	for _, volume := range volumes {
+		status := backup.SourceStatus(volume, sources[volume.Namespace+"/"+backup.SourceName(volume)], now, backupMaxAge)
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			volume.Key(),
+			formatStatusTime(status.LastSyncTime, now),
+			formatStatusDuration(status.LastSyncDuration),
+			formatStatusTime(status.NextSyncTime, now),
+			cmp.Or(status.Result, "-"),
+			formatConditions(status),
+			statusHealth(status),
+		)
+		if !status.Healthy() {
+			unhealthy = append(unhealthy, status)
+		}
+	}
+	if err := table.Flush(); err != nil {
+		return err
+	}
+
+	for _, status := range unhealthy {
+		log.Errorf("%s: %s", status.Volume.Key(), strings.Join(status.Problems, "; "))
+	}
+	if len(unhealthy) > 0 {
+		return fmt.Errorf("%d of %d volume(s) unhealthy", len(unhealthy), len(volumes))
+	}
+	return nil
+}
+
+// getReplicationSources returns every ReplicationSource in the cluster keyed
+// by namespace/name.
+func getReplicationSources(ctx context.Context) (map[string]*volsyncv1alpha1.ReplicationSource, error) {
+	output, err := runKubectl(ctx, "get", "replicationsources.volsync.backube", "--all-namespaces", "-o", "json")
+	if err != nil {
+		return nil, fmt.Errorf("get ReplicationSources: %w (output: %s)", err, strings.TrimSpace(string(output)))
+	}
+
+	var list volsyncv1alpha1.ReplicationSourceList
+	if err := json.Unmarshal(output, &list); err != nil {
+		return nil, fmt.Errorf("parse ReplicationSources: %w", err)
+	}
+	sources := map[string]*volsyncv1alpha1.ReplicationSource{}
+	for i := range list.Items {
+		source := &list.Items[i]
+		sources[source.Namespace+"/"+source.Name] = source
+	}
+	return sources, nil
+}
+
+func formatStatusTime(value *time.Time, now time.Time) string {
+	if value == nil {
+		return "-"
+	}
+	if value.After(now) {
+		return "in " + value.Sub(now).Round(time.Minute).String()
+	}
+	return now.Sub(*value).Round(time.Minute).String() + " ago"
+}
+
+func formatStatusDuration(duration time.Duration) string {
+	if duration == 0 {
+		return "-"
+	}
+	return duration.Round(time.Second).String()
+}
+
+func formatConditions(status backup.Status) string {
+	var conditions []string
+	for _, condition := range status.Conditions {
+		conditions = append(conditions, condition.Type+"="+condition.Reason)
+	}
+	return cmp.Or(strings.Join(conditions, ","), "-")
+}
+
+func statusHealth(status backup.Status) string {
+	switch {
+	case !status.Found:
+		return "missing"
+	case !status.Healthy():
+		return "unhealthy"
+	}
+	return "ok"
+}
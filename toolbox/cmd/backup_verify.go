@@ -0,0 +1,285 @@
+package cmd
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/khuedoan/cloudlab/toolbox/internal/backup"
+)
+
+const (
+	verifyCheckTimeout   = 10 * time.Minute
+	verifyCleanupTimeout = 10 * time.Minute
+)
+
+var (
+	backupVerifyNamespace        string
+	backupVerifyKeep             bool
+	backupVerifyCronJob          bool
+	backupVerifyCronJobNamespace string
+	backupVerifySchedule         string
+	backupVerifyImage            string
+)
+
+var backupVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Args:  cobra.NoArgs,
+	Short: "Restore volumes into a scratch namespace and check the restored data",
+	PreRunE: func(_ *cobra.Command, _ []string) error {
+		if backupVerifyCronJob {
+			if backupEnv == "" {
+				return fmt.Errorf("--env is required")
+			}
+			if backupVerifyImage == "" {
+				return fmt.Errorf("--image is required with --cronjob")
+			}
+			return nil
+		}
+		return validateBackupFlags()
+	},
+	RunE: runBackupVerify,
+}
+
+func init() {
+	backupVerifyCmd.Flags().StringVar(&backupVerifyNamespace, "namespace", "backup-verify", "Scratch namespace to restore into, deleted afterwards")
+	backupVerifyCmd.Flags().BoolVar(&backupVerifyKeep, "keep", false, "Keep the scratch namespace for inspection instead of deleting it")
+	backupVerifyCmd.Flags().BoolVar(&backupVerifyCronJob, "cronjob", false, "Print a CronJob manifest that runs verify in-cluster instead of running it")
+	backupVerifyCmd.Flags().StringVar(&backupVerifyCronJobNamespace, "cronjob-namespace", "volsync-system", "Namespace of the CronJob")
+	backupVerifyCmd.Flags().StringVar(&backupVerifySchedule, "schedule", "0 4 * * 0", "Cron schedule of the CronJob")
+	backupVerifyCmd.Flags().StringVar(&backupVerifyImage, "image", "", "Image containing toolbox and kubectl for the CronJob")
+}
+
+type verifyResult struct {
+	volume   backup.Volume
+	duration time.Duration
+	output   string
+	err      error
+}
+
+func runBackupVerify(cmd *cobra.Command, _ []string) error {
+	if backupVerifyCronJob {
+		return renderVerifyCronJob(cmd)
+	}
+
+	volumes, err := prepareBackupRun(cmd.Context(), false)
+	if err != nil {
+		return err
+	}
+	exists, err := checkScratchNamespace(cmd.Context(), backupVerifyNamespace, volumes)
+	if err != nil {
+		return err
+	}
+	// A scratch namespace that existed before, like the one of the verify
+	// CronJob, holds permissions and is emptied instead of deleted.
+	switch {
+	case backupVerifyKeep:
+	case exists:
+		defer emptyScratchNamespace(context.WithoutCancel(cmd.Context()), backupVerifyNamespace, volumes)
+	default:
+		defer deleteScratchNamespace(context.WithoutCancel(cmd.Context()), backupVerifyNamespace)
+	}
+
+	trigger := "verify-" + time.Now().UTC().Format("20060102T150405.000000000Z")
+	results := make([]verifyResult, 0, len(volumes))
+	failed := 0
+	for _, volume := range volumes {
+		start := time.Now()
+		output, err := verifyVolume(cmd.Context(), volume, trigger)
+		if err != nil {
+			log.Errorf("verify failed for %s: %v", volume.Key(), err)
+			failed++
+		} else {
+			log.Infof("verify passed for %s", volume.Key())
+		}
+		results = append(results, verifyResult{volume: volume, duration: time.Since(start), output: output, err: err})
+	}
+
+	table := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "VOLUME\tRESULT\tDURATION\tOUTPUT")
+	for _, result := range results {
+		status := "passed"
+		if result.err != nil {
+			status = "failed"
+		}
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\n", result.volume.Key(), status, result.duration.Round(time.Second), lastLine(result.output))
+	}
+	if err := table.Flush(); err != nil {
+		return err
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d volume(s) failed verification", failed, len(volumes))
+	}
+	return nil
+}
+
+// verifyVolume restores the latest snapshot of a volume into the scratch
+// namespace and runs its check pod, returning the check's output.
+func verifyVolume(ctx context.Context, volume backup.Volume, trigger string) (string, error) {
+	target, err := cloneTarget(ctx, volume, backup.VerifyTarget(volume, backupVerifyNamespace), "")
+	if err != nil {
+		return "", err
+	}
+
+	objects := append([]backup.Object{backup.VerifyNamespace(backupVerifyNamespace)}, backup.BuildCloneObjects(volume, target, backup.RestoreOptions{Trigger: trigger})...)
+	if err := applyCloneObjects(ctx, objects); err != nil {
+		return "", err
+	}
+	name := backup.CloneDestinationName(target)
+	if err := waitForManualSync(ctx, target.Namespace, "replicationdestination/"+name, trigger, restoreTimeout); err != nil {
+		return "", fmt.Errorf("wait for restore %s/%s: %w", target.Namespace, name, err)
+	}
+
+	pod := backup.VerifyPodName(target)
+	if output, err := runKubectl(ctx, "-n", target.Namespace, "delete", "pod", pod, "--ignore-not-found"); err != nil {
+		return "", fmt.Errorf("delete previous check pod: %w (output: %s)", err, strings.TrimSpace(string(output)))
+	}
+	if err := applyBackupObjects(ctx, []backup.Object{backup.BuildVerifyPod(volume, target)}); err != nil {
+		return "", err
+	}
+	phase, err := waitForPodCompletion(ctx, target.Namespace, pod, verifyCheckTimeout)
+	if err != nil {
+		return "", err
+	}
+
+	logs, err := runKubectl(ctx, "-n", target.Namespace, "logs", pod)
+	output := strings.TrimSpace(string(logs))
+	if err != nil {
+		return output, fmt.Errorf("get check pod logs: %w", err)
+	}
+	if phase != "Succeeded" {
+		return output, fmt.Errorf("check pod %s/%s %s: %s", target.Namespace, pod, strings.ToLower(phase), output)
+	}
+	return output, nil
+}
+
+// waitForPodCompletion waits until a pod has succeeded or failed and returns
+// its phase.
+func waitForPodCompletion(ctx context.Context, namespace, name string, timeout time.Duration) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+	for {
+		output, err := runKubectl(ctx, "-n", namespace, "get", "pod", name, "-o", "jsonpath={.status.phase}")
+		if err != nil {
+			return "", fmt.Errorf("get pod %s/%s: %w (output: %s)", namespace, name, err, strings.TrimSpace(string(output)))
+		}
+		if phase := strings.TrimSpace(string(output)); phase == "Succeeded" || phase == "Failed" {
+			return phase, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return "", fmt.Errorf("wait for pod %s/%s to complete: %w", namespace, name, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkScratchNamespace refuses to use an existing namespace that backup
+// verify did not create, and reports whether the namespace exists.
+func checkScratchNamespace(ctx context.Context, namespace string, volumes []backup.Volume) (bool, error) {
+	output, err := runKubectl(ctx, "get", "namespace", namespace, "--ignore-not-found", "-o", "name")
+	if err != nil {
+		return false, fmt.Errorf("get namespace %s: %w (output: %s)", namespace, err, strings.TrimSpace(string(output)))
+	}
+	exists := strings.TrimSpace(string(output)) != ""
+	managedBy := ""
+	if exists {
+		output, err := runKubectl(ctx, "get", "namespace", namespace, "-o", `jsonpath={.metadata.labels.app\.kubernetes\.io/managed-by}`)
+		if err != nil {
+			return false, fmt.Errorf("get labels of namespace %s: %w (output: %s)", namespace, err, strings.TrimSpace(string(output)))
+		}
+		managedBy = strings.TrimSpace(string(output))
+	}
+	return exists, backup.CheckScratchNamespace(namespace, exists, managedBy, volumes)
+}
+
+// emptyScratchNamespace deletes the objects that verify created in the
+// scratch namespace, check pods first so that the PVCs are released.
+func emptyScratchNamespace(ctx context.Context, namespace string, volumes []backup.Volume) {
+	log.Infof("deleting restored volumes from scratch namespace %s", namespace)
+	args := []string{"-n", namespace, "delete", "--ignore-not-found", "--timeout=" + verifyCleanupTimeout.String()}
+	for _, volume := range volumes {
+		target := backup.VerifyTarget(volume, namespace)
+		objects := append([]backup.Object{backup.BuildVerifyPod(volume, target)}, backup.BuildCloneObjects(volume, target, backup.RestoreOptions{})...)
+		for _, object := range objects {
+			if kind := object.GetObjectKind().GroupVersionKind().Kind; kind != "Namespace" {
+				args = append(args, strings.ToLower(kind)+"/"+object.GetName())
+			}
+		}
+	}
+	output, err := runKubectl(ctx, args...)
+	if err != nil {
+		log.Errorf("empty scratch namespace %s: %v (output: %s)", namespace, err, strings.TrimSpace(string(output)))
+		return
+	}
+	logCommandOutput(output)
+}
+
+func deleteScratchNamespace(ctx context.Context, namespace string) {
+	log.Infof("deleting scratch namespace %s", namespace)
+	output, err := runKubectl(ctx, "delete", "namespace", namespace, "--ignore-not-found", "--timeout="+verifyCleanupTimeout.String())
+	if err != nil {
+		log.Errorf("delete scratch namespace %s: %v (output: %s)", namespace, err, strings.TrimSpace(string(output)))
+		return
+	}
+	logCommandOutput(output)
+}
+
+// renderVerifyCronJob prints a CronJob that runs verify in-cluster with the
+// backup inventory of the environment.
+func renderVerifyCronJob(cmd *cobra.Command) error {
+	config, err := backup.LoadConfig(backupSettingsFile, backupEnv)
+	if err != nil {
+		return fmt.Errorf("load settings file: %w", err)
+	}
+	volumes, err := backup.ParseAndValidate(config)
+	if err != nil {
+		return fmt.Errorf("validate backup inventory: %w", err)
+	}
+	if _, err := backup.FilterVolumes(volumes, backupVolumeSelectors); err != nil {
+		return fmt.Errorf("select backup volumes: %w", err)
+	}
+	var settings bytes.Buffer
+	encoder := yaml.NewEncoder(&settings)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(config); err != nil {
+		return fmt.Errorf("marshal backup inventory: %w", err)
+	}
+
+	objects, err := backup.BuildVerifyCronJob(backup.VerifyCronJobOptions{
+		Namespace:        backupVerifyCronJobNamespace,
+		Schedule:         backupVerifySchedule,
+		Image:            backupVerifyImage,
+		Env:              backupEnv,
+		ScratchNamespace: backupVerifyNamespace,
+		Volumes:          backupVolumeSelectors,
+		Settings:         settings.Bytes(),
+	})
+	if err != nil {
+		return err
+	}
+	manifest, err := backup.RenderYAML(objects)
+	if err != nil {
+		return err
+	}
+	_, err = cmd.OutOrStdout().Write(manifest)
+	return err
+}
+
+func lastLine(output string) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	return cmp.Or(lines[len(lines)-1], "-")
+}
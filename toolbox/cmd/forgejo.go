@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/khuedoan/cloudlab/toolbox/internal/vendors"
+)
+
+const (
+	forgejoNamespace      = "forgejo"
+	forgejoService        = "svc/forgejo-http"
+	forgejoPort           = 3000
+	forgejoInternalURL    = "http://forgejo-http.forgejo.svc.cluster.local:3000"
+	forgejoAdminUsername  = "forgejo_admin"
+	forgejoAdminSecret    = "secret/forgejo/admin"
+	forgejoPasswordSecret = "password"
+)
+
+// connectForgejo forwards the in-cluster Forgejo and returns it as a git
+// remote authenticated with the admin credentials from Vault.
+func connectForgejo(ctx context.Context) (*vendors.GitRemote, func(), error) {
+	vault, stopVault, err := connectVault(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("connect to Vault: %w", err)
+	}
+	defer stopVault()
+
+	secret, err := vaultSecretReader{client: vault}.ReadSecret(ctx, forgejoAdminSecret)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read %s: %w", forgejoAdminSecret, err)
+	}
+	password, ok := secret[forgejoPasswordSecret].(string)
+	if !ok {
+		return nil, nil, fmt.Errorf("%s has no %s key", forgejoAdminSecret, forgejoPasswordSecret)
+	}
+
+	forward, err := startKubectlPortForward(ctx, forgejoNamespace, forgejoService, forgejoPort)
+	if err != nil {
+		return nil, nil, fmt.Errorf("forward forgejo: %w", err)
+	}
+
+	return &vendors.GitRemote{
+		URL:      "http://" + forward.addr,
+		Username: forgejoAdminUsername,
+		Password: password,
+
+		InternalURL: forgejoInternalURL,
+	}, forward.Close, nil
+}
@@ -8,6 +8,8 @@ import (
 	"os/exec"
 	"strings"
 	"time"
+
+	"github.com/charmbracelet/log"
 )
 
 type kubectlPortForward struct {
@@ -29,15 +31,80 @@ func runKubectlInput(ctx context.Context, input []byte, args ...string) ([]byte,
 }
 
 func startKubectlPortForward(ctx context.Context, namespace, resource string, remotePort int) (*kubectlPortForward, error) {
+	localPort, err := reserveLocalPort()
+	if err != nil {
+		return nil, err
+	}
+	return startKubectlPortForwardOn(ctx, namespace, resource, localPort, remotePort)
+}
+
+// startPersistentPortForward forwards a local port like
+// startKubectlPortForward, but restarts kubectl on the same port whenever it
+// exits, e.g. when the pod behind the service is rescheduled, until Close is
+// called.
+func startPersistentPortForward(ctx context.Context, namespace, resource string, remotePort int) (*kubectlPortForward, error) {
+	localPort, err := reserveLocalPort()
+	if err != nil {
+		return nil, err
+	}
+	forward, err := startKubectlPortForwardOn(ctx, namespace, resource, localPort, remotePort)
+	if err != nil {
+		return nil, err
+	}
+
+	supervisorCtx, cancel := context.WithCancel(ctx)
+	persistent := &kubectlPortForward{
+		addr:   forward.addr,
+		cancel: cancel,
+		done:   make(chan error, 1),
+	}
+	go func() {
+		defer close(persistent.done)
+		for {
+			select {
+			case err := <-forward.done:
+				log.Warnf("port-forward to %s/%s exited, restarting: %v", namespace, resource, err)
+			case <-supervisorCtx.Done():
+				forward.Close()
+				return
+			}
+
+			delay := time.Second
+			for {
+				forward, err = startKubectlPortForwardOn(supervisorCtx, namespace, resource, localPort, remotePort)
+				if err == nil {
+					break
+				}
+				if supervisorCtx.Err() != nil {
+					return
+				}
+				log.Warnf("restart port-forward to %s/%s: %v", namespace, resource, err)
+				select {
+				case <-time.After(delay):
+				case <-supervisorCtx.Done():
+					return
+				}
+				delay = min(delay*2, 30*time.Second)
+			}
+		}
+	}()
+
+	return persistent, nil
+}
+
+func reserveLocalPort() (int, error) {
 	listener, err := net.Listen("tcp", "127.0.0.1:0")
 	if err != nil {
-		return nil, fmt.Errorf("reserve local port: %w", err)
+		return 0, fmt.Errorf("reserve local port: %w", err)
 	}
 	localPort := listener.Addr().(*net.TCPAddr).Port
 	if err := listener.Close(); err != nil {
-		return nil, fmt.Errorf("release local port: %w", err)
+		return 0, fmt.Errorf("release local port: %w", err)
 	}
+	return localPort, nil
+}
 
+func startKubectlPortForwardOn(ctx context.Context, namespace, resource string, localPort, remotePort int) (*kubectlPortForward, error) {
 	portForwardCtx, cancel := context.WithCancel(ctx)
 	output := &bytes.Buffer{}
 	cmd := exec.CommandContext(
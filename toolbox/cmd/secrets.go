@@ -9,10 +9,14 @@ import (
 	"github.com/khuedoan/cloudlab/toolbox/internal/secrets"
 )
 
-var settingsFile string
+var (
+	settingsFile string
+	secretsEnv   string
+)
 
 func init() {
 	secretsCmd.Flags().StringVar(&settingsFile, "settings", "", "Path to settings YAML file")
+	secretsCmd.Flags().StringVar(&secretsEnv, "env", "", "Environment whose settings overlay to apply")
 	_ = secretsCmd.MarkFlagRequired("settings")
 }
 
@@ -26,7 +30,7 @@ var secretsCmd = &cobra.Command{
 }
 
 func runSecrets(cmd *cobra.Command, _ []string) error {
-	config, err := secrets.LoadConfig(settingsFile)
+	config, err := secrets.LoadConfig(settingsFile, secretsEnv)
 	if err != nil {
 		return fmt.Errorf("load settings file: %w", err)
 	}
@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/charmbracelet/log"
+	"github.com/spf13/cobra"
+
+	"github.com/khuedoan/cloudlab/toolbox/internal/backup"
+	"github.com/khuedoan/cloudlab/toolbox/internal/secrets"
+	"github.com/khuedoan/cloudlab/toolbox/internal/settings"
+	"github.com/khuedoan/cloudlab/toolbox/internal/vendors"
+)
+
+// settingsDocument is every section of the settings file.
+type settingsDocument struct {
+	Secrets secrets.Config `yaml:",inline"`
+	Backup  backup.Config  `yaml:",inline"`
+	Vendors vendors.Config `yaml:",inline"`
+}
+
+var settingsEnv string
+
+func init() {
+	settingsValidateCmd.Flags().StringVar(&settingsFile, "settings", "", "Path to settings YAML file")
+	settingsValidateCmd.Flags().StringVar(&settingsEnv, "env", "", "Environment whose settings overlay to apply")
+	_ = settingsValidateCmd.MarkFlagRequired("settings")
+
+	settingsCmd.AddCommand(settingsValidateCmd)
+	settingsCmd.AddCommand(settingsSchemaCmd)
+	rootCmd.AddCommand(settingsCmd)
+}
+
+var settingsCmd = &cobra.Command{
+	Use:   "settings",
+	Short: "Validate settings files and print their JSON Schema",
+}
+
+var settingsValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Args:  cobra.NoArgs,
+	Short: "Report unknown fields, wrong types and invalid values in the merged settings",
+	RunE:  runSettingsValidate,
+}
+
+var settingsSchemaCmd = &cobra.Command{
+	Use:   "schema",
+	Args:  cobra.NoArgs,
+	Short: "Print the JSON Schema of settings files",
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		encoder := json.NewEncoder(cmd.OutOrStdout())
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(settings.Schema(&settingsDocument{}))
+	},
+}
+
+func runSettingsValidate(_ *cobra.Command, _ []string) error {
+	merged, err := settings.Load(settingsFile, settingsEnv)
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		// Application metadata conflicts are all reported at once.
+		for _, err := range joined.Unwrap() {
+			log.Error(err.Error())
+		}
+		return fmt.Errorf("found %d error(s) in the settings", len(joined.Unwrap()))
+	}
+	if err != nil {
+		return fmt.Errorf("load settings file: %w", err)
+	}
+
+	// Structural errors are reported before semantic ones, which are only
+	// meaningful once every value has the right type.
+	errs := merged.Check(&settingsDocument{})
+	if len(errs) == 0 {
+		var document settingsDocument
+		if err := merged.Decode(&document); err != nil {
+			return err
+		}
+		if _, err := secrets.ParseAndValidate(&document.Secrets); err != nil {
+			errs = append(errs, merged.Locate(err)...)
+		}
+		if merged.Source("backups") != "" {
+			if _, err := backup.ParseAndValidate(&document.Backup); err != nil {
+				errs = append(errs, merged.Locate(err)...)
+			}
+		}
+		if _, err := vendors.ParseAndValidate(&document.Vendors); err != nil {
+			errs = append(errs, merged.Locate(err)...)
+		}
+	}
+
+	for _, err := range errs {
+		log.Error(err.Error())
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("found %d error(s) in the settings", len(errs))
+	}
+	log.Info("settings are valid")
+	return nil
+}
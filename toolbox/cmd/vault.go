@@ -55,3 +55,19 @@ func getLocalVaultToken(ctx context.Context) (string, error) {
 	}
 	return string(token), nil
 }
+
+type vaultSecretReader struct {
+	client *api.Client
+}
+
+func (r vaultSecretReader) ReadSecret(ctx context.Context, path string) (map[string]any, error) {
+	mount, secretPath, ok := strings.Cut(path, "/")
+	if !ok {
+		return nil, fmt.Errorf("invalid path %q: expected format mount/path", path)
+	}
+	secret, err := r.client.KVv2(mount).Get(ctx, secretPath)
+	if err != nil {
+		return nil, err
+	}
+	return secret.Data, nil
+}
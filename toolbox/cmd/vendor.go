@@ -1,46 +1,205 @@
 package cmd
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"slices"
 
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/log"
+	"github.com/mattn/go-isatty"
 	"github.com/spf13/cobra"
 
+	"github.com/khuedoan/cloudlab/toolbox/internal/settings"
 	"github.com/khuedoan/cloudlab/toolbox/internal/vendors"
 )
 
 var vendorCmd = &cobra.Command{
 	Use:   "vendor",
 	Args:  cobra.NoArgs,
-	Short: "Vendor charts and images from settings.yaml into the in-cluster registry",
+	Short: "Vendor charts, images, artifacts, files and git repositories from settings.yaml",
 	PreRunE: func(_ *cobra.Command, _ []string) error {
+		if err := requireSettingsFile(); err != nil {
+			return err
+		}
 		return requireExecutables("helm", "kubectl", "oras")
 	},
 	RunE: runSync,
 }
 
+var (
+	vendorEnv      string
+	vendorOutput   string
+	vendorSelector vendors.Selector
+)
+
 func init() {
-	vendorCmd.Flags().StringVar(&settingsFile, "settings", "", "Path to settings YAML file")
-	_ = vendorCmd.MarkFlagRequired("settings")
+	vendorCmd.Flags().StringVar(&vendorOutput, "output", "text", "Output format: text or json")
+	addRegistryFlag(vendorCmd)
+	addSelectorFlags(vendorCmd)
+
+	// Not marked as required because vendor import reads a bundle instead.
+	vendorCmd.PersistentFlags().StringVar(&settingsFile, "settings", "", "Path to settings YAML file")
+	vendorCmd.PersistentFlags().StringVar(&vendorEnv, "env", "", "Environment whose settings overlay to apply and, for discover and check-refs, whose platform manifests to read")
+
+	vendorCmd.AddCommand(vendorDiscoverCmd)
+	vendorCmd.AddCommand(vendorOutdatedCmd)
+	vendorCmd.AddCommand(vendorPruneCmd)
+	vendorCmd.AddCommand(vendorExportCmd)
+	vendorCmd.AddCommand(vendorImportCmd)
+	vendorCmd.AddCommand(vendorCheckRefsCmd)
 }
 
 func runSync(cmd *cobra.Command, _ []string) error {
-	entries, err := vendors.LoadVendors(settingsFile)
+	if vendorOutput != "text" && vendorOutput != "json" {
+		return fmt.Errorf("invalid --output %q: must be text or json", vendorOutput)
+	}
+
+	workdir, err := os.MkdirTemp("", "toolbox-vendor-*")
+	if err != nil {
+		return fmt.Errorf("create temp dir: %w", err)
+	}
+	defer os.RemoveAll(workdir)
+
+	entries, err := loadVendorEntries(cmd.Context(), workdir)
 	if err != nil {
 		return err
 	}
+	if entries, err = vendors.FilterEntries(entries, vendorSelector); err != nil {
+		return fmt.Errorf("select vendor entries: %w", err)
+	}
+	if slices.ContainsFunc(entries, vendors.VendorEntry.NeedsCosign) {
+		if err := requireExecutables("cosign"); err != nil {
+			return err
+		}
+	}
 
-	tunnel, err := startKubectlPortForward(cmd.Context(), registryNamespace, registryService, registryPort)
+	destinations, stopDestinations, err := connectDestinations(cmd.Context(), workdir)
 	if err != nil {
-		return fmt.Errorf("forward registry: %w", err)
+		return err
 	}
-	defer tunnel.Close()
+	defer stopDestinations()
 
-	workdir, err := os.MkdirTemp("", "toolbox-vendor-*")
+	options := vendors.SyncOptions{Destinations: destinations, Workdir: workdir}
+	if slices.ContainsFunc(entries, vendors.VendorEntry.NeedsGit) {
+		if err := requireExecutables("git"); err != nil {
+			return err
+		}
+		remote, stopForgejo, err := connectForgejo(cmd.Context())
+		if err != nil {
+			return err
+		}
+		defer stopForgejo()
+		options.Git = remote
+	}
+
+	var results []vendors.Result
+	if vendorOutput == "text" && isatty.IsTerminal(os.Stdout.Fd()) {
+		results, err = syncWithProgress(cmd.Context(), options, entries)
+	} else {
+		results, err = vendors.Sync(cmd.Context(), options, entries)
+	}
+
+	if vendorOutput == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if results == nil {
+			results = []vendors.Result{}
+		}
+		if encodeErr := encoder.Encode(results); encodeErr != nil {
+			return errors.Join(err, fmt.Errorf("write report: %w", encodeErr))
+		}
+	}
+	return err
+}
+
+// syncWithProgress runs the sync behind a progress view, with log lines
+// printed above it. Interrupting the view cancels the sync.
+func syncWithProgress(ctx context.Context, options vendors.SyncOptions, entries []vendors.VendorEntry) ([]vendors.Result, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	total := 0
+	for _, entry := range entries {
+		if entry.Kind == "git" {
+			total += len(entry.Versions)
+		} else {
+			total += len(entry.Versions) * len(options.Destinations)
+		}
+	}
+	program := tea.NewProgram(newSyncProgress(total, len(options.Destinations) > 1, cancel))
+	options.Reporter = programReporter{program: program}
+
+	log.SetOutput(programLogWriter{program: program})
+
+	var results []vendors.Result
+	var syncErr error
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		results, syncErr = vendors.Sync(ctx, options, entries)
+		program.Send(progressDoneMsg{})
+	}()
+
+	_, err := program.Run()
+	// The program can exit before the sync does, e.g. when it is killed, so
+	// the remaining log lines go to stderr.
+	log.SetOutput(os.Stderr)
+	cancel()
+	<-done
 	if err != nil {
-		return fmt.Errorf("create temp dir: %w", err)
+		return results, errors.Join(syncErr, fmt.Errorf("progress view: %w", err))
+	}
+	return results, syncErr
+}
+
+// loadVendorEntries loads the vendor entries and resolves their upstream
+// credentials into workdir, connecting to Vault only when an entry
+// references it.
+func loadVendorEntries(ctx context.Context, workdir string) ([]vendors.VendorEntry, error) {
+	entries, err := vendors.LoadVendors(settingsFile, vendorEnv)
+	if err != nil {
+		return nil, err
 	}
-	defer os.RemoveAll(workdir)
 
-	return vendors.Sync(cmd.Context(), workdir, tunnel.addr, entries)
+	err = withVendorSecrets(ctx, slices.ContainsFunc(entries, vendors.VendorEntry.NeedsVault), func(secrets vendors.SecretReader) error {
+		return vendors.ResolveCredentials(ctx, entries, secrets, workdir)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func addSelectorFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(&vendorSelector.Only, "only", nil, "Only process the entry with this exact name (repeatable)")
+	cmd.Flags().StringSliceVar(&vendorSelector.Kinds, "kind", nil, "Only process entries of this kind: chart, image, artifact, file or git (repeatable)")
+	cmd.Flags().StringSliceVar(&vendorSelector.Match, "match", nil, "Only process entries whose name matches this glob, e.g. 'vendor/charts/*' (repeatable)")
+	cmd.Flags().StringSliceVar(&vendorSelector.Versions, "version", nil, "Only process this version of the selected entries (repeatable)")
+}
+
+// settingsFileFor returns the settings file that defines the value at keys,
+// so that edits land where the value is configured. It falls back to the
+// main settings file for values that are not configured yet, and for values
+// from application metadata, which is JSON.
+func settingsFileFor(keys ...string) (string, error) {
+	merged, err := settings.Load(settingsFile, vendorEnv)
+	if err != nil {
+		return "", fmt.Errorf("load settings file: %w", err)
+	}
+	if file := merged.Source(keys...); file != "" && filepath.Ext(file) != ".json" {
+		return file, nil
+	}
+	return settingsFile, nil
+}
+
+func requireSettingsFile() error {
+	if settingsFile == "" {
+		return fmt.Errorf("required flag \"settings\" not set")
+	}
+	return nil
 }
@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/charmbracelet/log"
+	"github.com/spf13/cobra"
+
+	"github.com/khuedoan/cloudlab/toolbox/internal/vendors"
+)
+
+var vendorExportOutput string
+
+func init() {
+	vendorExportCmd.Flags().StringVar(&vendorExportOutput, "output", "", "Path to the bundle tarball to write")
+	_ = vendorExportCmd.MarkFlagRequired("output")
+	addSelectorFlags(vendorExportCmd)
+
+	addRegistryFlag(vendorImportCmd)
+}
+
+var vendorExportCmd = &cobra.Command{
+	Use:   "export",
+	Args:  cobra.NoArgs,
+	Short: "Write the selected vendor entries into a tarball of OCI image layouts and git bundles",
+	PreRunE: func(_ *cobra.Command, _ []string) error {
+		if err := requireSettingsFile(); err != nil {
+			return err
+		}
+		return requireExecutables("helm", "oras")
+	},
+	RunE: runVendorExport,
+}
+
+var vendorImportCmd = &cobra.Command{
+	Use:   "import <bundle>",
+	Args:  cobra.ExactArgs(1),
+	Short: "Push a bundle written by vendor export into the selected registries and Forgejo",
+	PreRunE: func(_ *cobra.Command, _ []string) error {
+		return requireExecutables("kubectl", "oras")
+	},
+	RunE: runVendorImport,
+}
+
+func runVendorExport(cmd *cobra.Command, _ []string) error {
+	workdir, err := os.MkdirTemp("", "toolbox-vendor-*")
+	if err != nil {
+		return fmt.Errorf("create temp dir: %w", err)
+	}
+	defer os.RemoveAll(workdir)
+
+	entries, err := loadVendorEntries(cmd.Context(), workdir)
+	if err != nil {
+		return err
+	}
+	if entries, err = vendors.FilterEntries(entries, vendorSelector); err != nil {
+		return fmt.Errorf("select vendor entries: %w", err)
+	}
+	if slices.ContainsFunc(entries, vendors.VendorEntry.NeedsCosign) {
+		if err := requireExecutables("cosign"); err != nil {
+			return err
+		}
+	}
+	if slices.ContainsFunc(entries, vendors.VendorEntry.NeedsGit) {
+		if err := requireExecutables("git"); err != nil {
+			return err
+		}
+	}
+
+	if err := vendors.Export(cmd.Context(), workdir, vendorExportOutput, entries); err != nil {
+		return err
+	}
+
+	log.Infof("exported %d vendor entry(s) to %s", len(entries), vendorExportOutput)
+	return nil
+}
+
+func runVendorImport(cmd *cobra.Command, args []string) error {
+	workdir, err := os.MkdirTemp("", "toolbox-vendor-*")
+	if err != nil {
+		return fmt.Errorf("create temp dir: %w", err)
+	}
+	defer os.RemoveAll(workdir)
+
+	destinations, stopDestinations, err := connectDestinations(cmd.Context(), workdir)
+	if err != nil {
+		return err
+	}
+	defer stopDestinations()
+
+	hasGit, err := vendors.BundleHasGit(args[0])
+	if err != nil {
+		return err
+	}
+	var remote *vendors.GitRemote
+	if hasGit {
+		if err := requireExecutables("git"); err != nil {
+			return err
+		}
+		var stopForgejo func()
+		remote, stopForgejo, err = connectForgejo(cmd.Context())
+		if err != nil {
+			return err
+		}
+		defer stopForgejo()
+	}
+
+	if err := vendors.Import(cmd.Context(), workdir, args[0], destinations, remote); err != nil {
+		return err
+	}
+
+	log.Infof("imported %s", args[0])
+	return nil
+}
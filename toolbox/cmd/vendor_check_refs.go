@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/charmbracelet/log"
+	"github.com/spf13/cobra"
+
+	"github.com/khuedoan/cloudlab/toolbox/internal/vendors"
+)
+
+var (
+	vendorCheckRefsPlatform string
+	vendorCheckRefsFix      bool
+)
+
+func init() {
+	vendorCheckRefsCmd.Flags().StringVar(&vendorCheckRefsPlatform, "platform", "platform", "Path to the platform manifests directory")
+	vendorCheckRefsCmd.Flags().BoolVar(&vendorCheckRefsFix, "fix", false, "Rewrite upstream references to their vendored equivalents")
+}
+
+var vendorCheckRefsCmd = &cobra.Command{
+	Use:   "check-refs",
+	Args:  cobra.NoArgs,
+	Short: "Find platform manifest references that bypass or do not match the vendored artifacts",
+	PreRunE: func(_ *cobra.Command, _ []string) error {
+		return requireSettingsFile()
+	},
+	RunE: runVendorCheckRefs,
+}
+
+func runVendorCheckRefs(cmd *cobra.Command, _ []string) error {
+	// Every environment is checked against its own settings overlay unless
+	// one is given.
+	envs := []string{vendorEnv}
+	if vendorEnv == "" {
+		dirs, err := os.ReadDir(vendorCheckRefsPlatform)
+		if err != nil {
+			return fmt.Errorf("read platform directory: %w", err)
+		}
+		envs = envs[:0]
+		for _, dir := range dirs {
+			if dir.IsDir() {
+				envs = append(envs, dir.Name())
+			}
+		}
+	}
+
+	remaining := 0
+	for _, env := range envs {
+		entries, err := vendors.LoadVendors(settingsFile, env)
+		if err != nil {
+			return fmt.Errorf("check %s: %w", env, err)
+		}
+		issues, err := vendors.CheckRefs(filepath.Join(vendorCheckRefsPlatform, env), entries, vendorCheckRefsFix)
+		if err != nil {
+			return fmt.Errorf("check %s: %w", env, err)
+		}
+		for _, issue := range issues {
+			if issue.Fixed {
+				log.Infof("fixed %s", issue)
+				continue
+			}
+			remaining++
+			log.Warn(issue.String())
+		}
+	}
+
+	if remaining > 0 {
+		return fmt.Errorf("found %d reference(s) that do not match the vendored artifacts", remaining)
+	}
+	log.Info("all references match the vendored artifacts")
+	return nil
+}
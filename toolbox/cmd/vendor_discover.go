@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/charmbracelet/log"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/khuedoan/cloudlab/toolbox/internal/vendors"
+)
+
+var (
+	vendorDiscoverPlatform string
+	vendorDiscoverAppend   bool
+)
+
+func init() {
+	vendorDiscoverCmd.Flags().StringVar(&vendorDiscoverPlatform, "platform", "platform", "Path to the platform manifests directory")
+	vendorDiscoverCmd.Flags().BoolVar(&vendorDiscoverAppend, "append", false, "Append missing image entries to the settings file instead of printing them")
+}
+
+var vendorDiscoverCmd = &cobra.Command{
+	Use:   "discover",
+	Args:  cobra.NoArgs,
+	Short: "Find container images referenced by vendored charts that are not vendored",
+	PreRunE: func(_ *cobra.Command, _ []string) error {
+		if err := requireSettingsFile(); err != nil {
+			return err
+		}
+		return requireExecutables("helm")
+	},
+	RunE: runVendorDiscover,
+}
+
+func runVendorDiscover(cmd *cobra.Command, _ []string) error {
+	workdir, err := os.MkdirTemp("", "toolbox-vendor-*")
+	if err != nil {
+		return fmt.Errorf("create temp dir: %w", err)
+	}
+	defer os.RemoveAll(workdir)
+
+	entries, err := loadVendorEntries(cmd.Context(), workdir)
+	if err != nil {
+		return err
+	}
+
+	platformDir := ""
+	// Charts are rendered with values from the matching HelmReleases of the
+	// environment when one is given.
+	if vendorEnv != "" {
+		platformDir = filepath.Join(vendorDiscoverPlatform, vendorEnv)
+	}
+
+	result, err := vendors.Discover(cmd.Context(), workdir, platformDir, entries)
+	if err != nil {
+		return err
+	}
+
+	for _, image := range result.Unknown {
+		log.Warnf("image %s is not vendored by any entry", image)
+	}
+	if len(result.Missing) == 0 {
+		log.Info("all discovered images are vendored")
+		return nil
+	}
+
+	if vendorDiscoverAppend {
+		file, err := settingsFileFor("vendors")
+		if err != nil {
+			return err
+		}
+		if err := vendors.AppendEntries(file, result.Missing); err != nil {
+			return fmt.Errorf("update settings file: %w", err)
+		}
+		log.Infof("appended %d image entry(s) to %s", len(result.Missing), file)
+		return nil
+	}
+
+	missing := map[string]vendors.Vendor{}
+	for _, entry := range result.Missing {
+		missing[entry.Name] = entry.Vendor
+	}
+	encoder := yaml.NewEncoder(cmd.OutOrStdout())
+	encoder.SetIndent(2)
+	if err := encoder.Encode(vendors.Config{Items: missing}); err != nil {
+		return fmt.Errorf("render missing entries: %w", err)
+	}
+	return encoder.Close()
+}
@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"text/tabwriter"
+
+	"github.com/charmbracelet/log"
+	"github.com/spf13/cobra"
+
+	"github.com/khuedoan/cloudlab/toolbox/internal/settings"
+	"github.com/khuedoan/cloudlab/toolbox/internal/vendors"
+)
+
+var vendorOutdatedUpdate string
+
+func init() {
+	vendorOutdatedCmd.Flags().StringVar(&vendorOutdatedUpdate, "update", "", "Rewrite versions in the settings file to the latest patch, minor or major release")
+	addSelectorFlags(vendorOutdatedCmd)
+}
+
+var vendorOutdatedCmd = &cobra.Command{
+	Use:   "outdated",
+	Args:  cobra.NoArgs,
+	Short: "Report vendored charts and images with newer upstream versions",
+	PreRunE: func(_ *cobra.Command, _ []string) error {
+		if err := requireSettingsFile(); err != nil {
+			return err
+		}
+		if vendorOutdatedUpdate != "" && !slices.Contains([]string{"patch", "minor", "major"}, vendorOutdatedUpdate) {
+			return fmt.Errorf("--update must be one of patch, minor or major")
+		}
+		return requireExecutables("oras")
+	},
+	RunE: runVendorOutdated,
+}
+
+func runVendorOutdated(cmd *cobra.Command, _ []string) error {
+	workdir, err := os.MkdirTemp("", "toolbox-vendor-*")
+	if err != nil {
+		return fmt.Errorf("create temp dir: %w", err)
+	}
+	defer os.RemoveAll(workdir)
+
+	entries, err := loadVendorEntries(cmd.Context(), workdir)
+	if err != nil {
+		return err
+	}
+	selected, err := vendors.FilterEntries(entries, vendorSelector)
+	if err != nil {
+		return fmt.Errorf("select vendor entries: %w", err)
+	}
+
+	outdated, err := vendors.CheckOutdated(cmd.Context(), selected)
+	if err != nil {
+		return err
+	}
+	if len(outdated) == 0 {
+		log.Info("all vendored versions are up to date")
+		return nil
+	}
+
+	table := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "NAME\tCURRENT\tPATCH\tMINOR\tMAJOR")
+	for _, item := range outdated {
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\n", item.Name, item.Current, orDash(item.Patch), orDash(item.Minor), orDash(item.Major))
+	}
+	if err := table.Flush(); err != nil {
+		return err
+	}
+
+	if vendorOutdatedUpdate == "" {
+		return nil
+	}
+
+	var updates []vendors.VersionUpdate
+	planned := map[string]bool{}
+	for _, item := range outdated {
+		target := item.Target(vendorOutdatedUpdate)
+		if target == "" {
+			continue
+		}
+		if planned[item.Name+"@"+target] || vendorVersionListed(entries, item.Name, target) {
+			log.Warnf("not updating %s@%s: %s is already listed", item.Name, item.Current, target)
+			continue
+		}
+		planned[item.Name+"@"+target] = true
+		updates = append(updates, vendors.VersionUpdate{Name: item.Name, From: item.Current, To: target})
+	}
+
+	// Versions can be configured in an included or overlay file, so every
+	// update is written to the file that defines its versions.
+	merged, err := settings.Load(settingsFile, vendorEnv)
+	if err != nil {
+		return fmt.Errorf("load settings file: %w", err)
+	}
+	byFile := map[string][]vendors.VersionUpdate{}
+	var files []string
+	for _, update := range updates {
+		file := merged.Source("vendors", update.Name, "versions")
+		if _, ok := byFile[file]; !ok {
+			files = append(files, file)
+		}
+		byFile[file] = append(byFile[file], update)
+	}
+	for _, file := range files {
+		if err := vendors.UpdateVersions(file, byFile[file]); err != nil {
+			return fmt.Errorf("update settings file: %w", err)
+		}
+		log.Infof("updated %d version(s) in %s", len(byFile[file]), file)
+	}
+	return nil
+}
+
+func vendorVersionListed(entries []vendors.VendorEntry, name, version string) bool {
+	for _, entry := range entries {
+		if entry.Name == name && slices.Contains(entry.Versions, version) {
+			return true
+		}
+	}
+	return false
+}
+
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
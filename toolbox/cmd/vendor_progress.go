@@ -0,0 +1,205 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/spinner"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/dustin/go-humanize"
+
+	"github.com/khuedoan/cloudlab/toolbox/internal/vendors"
+)
+
+var (
+	progressDimStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+	progressDoneStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("2"))
+	progressErrorStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("1"))
+)
+
+type (
+	progressStartMsg struct {
+		result  vendors.Result
+		started time.Time
+	}
+	progressBytesMsg struct {
+		result vendors.Result
+		bytes  int64
+	}
+	progressFinishMsg struct{ result vendors.Result }
+	progressDoneMsg   struct{}
+)
+
+type progressRow struct {
+	started  time.Time
+	bytes    int64
+	finished bool
+	result   vendors.Result
+}
+
+// syncProgress shows one row per entry version and registry with the bytes
+// transferred and the status of the transfer. Only file downloads report bytes
+// while they run; helm, oras and git do not, so the other kinds show their
+// total size once they finish.
+type syncProgress struct {
+	total    int
+	registry bool
+	rows     []progressRow
+	spinner  spinner.Model
+	cancel   func()
+	canceled bool
+}
+
+// newSyncProgress returns a progress view for total transfers. The registry
+// column is only shown when syncing to more than one registry.
+func newSyncProgress(total int, registry bool, cancel func()) syncProgress {
+	return syncProgress{
+		total:    total,
+		registry: registry,
+		spinner:  spinner.New(spinner.WithSpinner(spinner.Dot)),
+		cancel:   cancel,
+	}
+}
+
+func (m syncProgress) Init() tea.Cmd {
+	return m.spinner.Tick
+}
+
+func (m syncProgress) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		if msg.String() == "ctrl+c" {
+			m.canceled = true
+			m.cancel()
+		}
+	case progressStartMsg:
+		m.rows = append(m.rows, progressRow{started: msg.started, result: msg.result})
+	case progressBytesMsg:
+		if row := m.row(msg.result); row != nil {
+			row.bytes = msg.bytes
+		}
+	case progressFinishMsg:
+		if row := m.row(msg.result); row != nil {
+			row.finished = true
+			row.result = msg.result
+			row.bytes = max(row.bytes, msg.result.Size)
+		}
+	case progressDoneMsg:
+		return m, tea.Quit
+	case spinner.TickMsg:
+		var cmd tea.Cmd
+		m.spinner, cmd = m.spinner.Update(msg)
+		return m, cmd
+	}
+	return m, nil
+}
+
+func (m syncProgress) row(result vendors.Result) *progressRow {
+	for i := len(m.rows) - 1; i >= 0; i-- {
+		row := m.rows[i].result
+		if row.Name == result.Name && row.Version == result.Version && row.Registry == result.Registry {
+			return &m.rows[i]
+		}
+	}
+	return nil
+}
+
+func (m syncProgress) View() string {
+	nameWidth, versionWidth, registryWidth := 0, 0, 0
+	for _, row := range m.rows {
+		nameWidth = max(nameWidth, len(row.result.Name))
+		versionWidth = max(versionWidth, len(row.result.Version))
+		if m.registry {
+			registryWidth = max(registryWidth, len(row.result.Registry)+2)
+		}
+	}
+
+	var view strings.Builder
+	done := 0
+	for _, row := range m.rows {
+		status := m.spinner.View()
+		elapsed := time.Since(row.started)
+		if row.finished {
+			done++
+			elapsed = row.result.Duration
+			status = progressDoneStyle.Render("✓")
+			if row.result.Error != "" {
+				status = progressErrorStyle.Render("✗")
+			}
+		}
+		registry := ""
+		if m.registry {
+			registry = "  " + row.result.Registry
+		}
+		size := humanize.IBytes(uint64(row.bytes))
+		if !reportsSize(row.result.Kind, row.finished) {
+			size = "-"
+		}
+		fmt.Fprintf(&view, "%s %-*s  %-*s%-*s  %10s  %s\n",
+			status,
+			nameWidth, row.result.Name,
+			versionWidth, row.result.Version,
+			registryWidth, registry,
+			size,
+			progressDimStyle.Render(elapsed.Round(time.Second).String()),
+		)
+	}
+
+	summary := fmt.Sprintf("%d/%d transfers", done, m.total)
+	if slices.ContainsFunc(m.rows, func(row progressRow) bool {
+		return !reportsSize(row.result.Kind, row.finished) && reportsSize(row.result.Kind, true)
+	}) {
+		summary += ", sizes of charts, images and artifacts are shown once they finish"
+	}
+	if m.canceled {
+		summary += ", canceling"
+	}
+	view.WriteString(progressDimStyle.Render(summary) + "\n")
+	return view.String()
+}
+
+// programReporter forwards sync progress to a running syncProgress program.
+type programReporter struct {
+	program *tea.Program
+}
+
+func (r programReporter) Start(result vendors.Result) {
+	r.program.Send(progressStartMsg{result: result, started: time.Now()})
+}
+
+func (r programReporter) Progress(result vendors.Result, bytes int64) {
+	r.program.Send(progressBytesMsg{result: result, bytes: bytes})
+}
+
+func (r programReporter) Finish(result vendors.Result) {
+	r.program.Send(progressFinishMsg{result: result})
+}
+
+// programLogWriter prints log lines above the progress view instead of
+// letting them overwrite it. Unlike Program.Println, Send drops the line
+// instead of blocking once the program has exited.
+type programLogWriter struct {
+	program *tea.Program
+}
+
+func (w programLogWriter) Write(p []byte) (int, error) {
+	w.program.Send(tea.Println(strings.TrimRight(string(p), "\n"))())
+	return len(p), nil
+}
+
+// reportsSize reports whether a transfer of kind has a size to show: files
+// report bytes as they download, charts, images and artifacts only once they
+// finish, and git mirrors never.
+func reportsSize(kind string, finished bool) bool {
+	switch kind {
+	case "file":
+		return true
+	case "git":
+		return false
+	default:
+		return finished
+	}
+}
@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/spf13/cobra"
+
+	"github.com/khuedoan/cloudlab/toolbox/internal/vendors"
+)
+
+var (
+	vendorPruneDryRun     bool
+	vendorPruneCheckInUse bool
+)
+
+func init() {
+	vendorPruneCmd.Flags().BoolVar(&vendorPruneDryRun, "dry-run", false, "Only list unreferenced artifacts without deleting them")
+	vendorPruneCmd.Flags().BoolVar(&vendorPruneCheckInUse, "check-in-use", false, "Keep artifacts still referenced by running pods or HelmReleases")
+	addRegistryFlag(vendorPruneCmd)
+}
+
+var vendorPruneCmd = &cobra.Command{
+	Use:   "prune",
+	Args:  cobra.NoArgs,
+	Short: "Delete vendored artifacts that are no longer listed in settings.yaml",
+	PreRunE: func(_ *cobra.Command, _ []string) error {
+		if err := requireSettingsFile(); err != nil {
+			return err
+		}
+		return requireExecutables("kubectl", "oras")
+	},
+	RunE: runVendorPrune,
+}
+
+func runVendorPrune(cmd *cobra.Command, _ []string) error {
+	entries, err := vendors.LoadVendors(settingsFile, vendorEnv)
+	if err != nil {
+		return err
+	}
+
+	workdir, err := os.MkdirTemp("", "toolbox-vendor-*")
+	if err != nil {
+		return fmt.Errorf("create temp dir: %w", err)
+	}
+	defer os.RemoveAll(workdir)
+
+	destinations, stopDestinations, err := connectDestinations(cmd.Context(), workdir)
+	if err != nil {
+		return err
+	}
+	defer stopDestinations()
+
+	inUse := map[string]bool{}
+	if vendorPruneCheckInUse {
+		if inUse, err = vendoredArtifactsInUse(cmd.Context()); err != nil {
+			return fmt.Errorf("find artifacts in use: %w", err)
+		}
+	}
+
+	for _, registry := range destinations {
+		artifacts, err := vendors.ListUnreferenced(cmd.Context(), registry, entries)
+		if err != nil {
+			return fmt.Errorf("list %s: %w", registry.Name, err)
+		}
+
+		deleted := 0
+		for _, artifact := range artifacts {
+			if inUse[artifact.String()] {
+				log.Warnf("keeping %s in %s: still referenced in the cluster", artifact, registry.Name)
+				continue
+			}
+			if vendorPruneDryRun {
+				log.Infof("would delete %s from %s", artifact, registry.Name)
+				continue
+			}
+			log.Infof("deleting %s from %s", artifact, registry.Name)
+			if err := vendors.DeleteArtifact(cmd.Context(), registry, artifact); err != nil {
+				return err
+			}
+			deleted++
+		}
+		log.Infof("deleted %d of %d unreferenced artifact(s) from %s", deleted, len(artifacts), registry.Name)
+	}
+	return nil
+}
+
+// vendoredArtifactsInUse returns the internal registry artifacts referenced by
+// pod containers and by HelmReleases through vendored HelmRepositories.
+func vendoredArtifactsInUse(ctx context.Context) (map[string]bool, error) {
+	inUse := map[string]bool{}
+
+	output, err := runKubectl(ctx, "get", "pods", "--all-namespaces", "-o",
+		`jsonpath={range .items[*]}{range .spec.initContainers[*]}{.image}{"\n"}{end}{range .spec.containers[*]}{.image}{"\n"}{end}{end}`)
+	if err != nil {
+		return nil, fmt.Errorf("list pod images: %w (output: %s)", err, strings.TrimSpace(string(output)))
+	}
+	for _, ref := range strings.Fields(string(output)) {
+		image, err := vendors.ParseImageReference(ref)
+		if err == nil && image.Vendored() && image.Tag != "" {
+			inUse[image.Repository+":"+image.Tag] = true
+		}
+	}
+
+	output, err = runKubectl(ctx, "get", "helmrepositories", "--all-namespaces", "-o",
+		`jsonpath={range .items[*]}{.metadata.namespace}/{.metadata.name} {.spec.url}{"\n"}{end}`)
+	if err != nil {
+		return nil, fmt.Errorf("list HelmRepositories: %w (output: %s)", err, strings.TrimSpace(string(output)))
+	}
+	repositories := map[string]string{}
+	for line := range strings.Lines(string(output)) {
+		if name, url, ok := strings.Cut(strings.TrimSpace(line), " "); ok {
+			repositories[name] = url
+		}
+	}
+
+	output, err = runKubectl(ctx, "get", "helmreleases", "--all-namespaces", "-o",
+		`jsonpath={range .items[*]}{.metadata.namespace}/{.spec.chart.spec.sourceRef.name} {.spec.chart.spec.chart} {.spec.chart.spec.version}{"\n"}{end}`)
+	if err != nil {
+		return nil, fmt.Errorf("list HelmReleases: %w (output: %s)", err, strings.TrimSpace(string(output)))
+	}
+	for line := range strings.Lines(string(output)) {
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			continue
+		}
+		repository, ok := strings.CutPrefix(repositories[fields[0]], "oci://"+vendors.InternalRegistry+"/")
+		if ok {
+			inUse[repository+"/"+fields[1]+":"+vendors.RegistryTag(fields[2])] = true
+		}
+	}
+
+	return inUse, nil
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/spf13/cobra"
+
+	"github.com/khuedoan/cloudlab/toolbox/internal/vendors"
+)
+
+var vendorRegistries []string
+
+func addRegistryFlag(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(&vendorRegistries, "registry", []string{vendors.InClusterRegistry}, "Registry to push to: in-cluster or a name from the registries settings (repeatable)")
+}
+
+// connectDestinations resolves the registries selected with --registry, with
+// their credentials in workdir, and forwards the in-cluster registry when it
+// is one of them.
+func connectDestinations(ctx context.Context, workdir string) ([]vendors.Destination, func(), error) {
+	if slices.ContainsFunc(vendorRegistries, func(name string) bool { return name != vendors.InClusterRegistry }) {
+		if err := requireSettingsFile(); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	destinations, err := vendors.LoadDestinations(settingsFile, vendorEnv, vendorRegistries)
+	if err != nil {
+		return nil, nil, err
+	}
+	err = withVendorSecrets(ctx, slices.ContainsFunc(destinations, vendors.Destination.NeedsVault), func(secrets vendors.SecretReader) error {
+		return vendors.ResolveDestinationCredentials(ctx, destinations, secrets, workdir)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	stop := func() {}
+	for i, destination := range destinations {
+		if destination.Name != vendors.InClusterRegistry {
+			continue
+		}
+		tunnel, err := startPersistentPortForward(ctx, registryNamespace, registryService, registryPort)
+		if err != nil {
+			return nil, nil, fmt.Errorf("forward registry: %w", err)
+		}
+		destinations[i].Address = tunnel.addr
+		stop = tunnel.Close
+	}
+	return destinations, stop, nil
+}
+
+// withVendorSecrets calls fn with a Vault secret reader when needed, and with
+// nil otherwise so that Vault is only required when settings reference it.
+func withVendorSecrets(ctx context.Context, needed bool, fn func(vendors.SecretReader) error) error {
+	if !needed {
+		return fn(nil)
+	}
+	vault, stopVault, err := connectVault(ctx)
+	if err != nil {
+		return fmt.Errorf("connect to Vault: %w", err)
+	}
+	defer stopVault()
+	return fn(vaultSecretReader{client: vault})
+}
@@ -2,42 +2,129 @@ package backup
 
 import (
 	"cmp"
+	"errors"
 	"fmt"
-	"os"
+	"maps"
 	"slices"
 	"strings"
+	"time"
 
 	volsyncv1alpha1 "github.com/backube/volsync/api/v1alpha1"
 	"gopkg.in/yaml.v3"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/utils/ptr"
 	k8syaml "sigs.k8s.io/yaml"
+
+	"github.com/khuedoan/cloudlab/toolbox/internal/settings"
 )
 
 const (
-	schedule           = "*/30 * * * *"
-	pruneInterval      = 14
-	enableFileDeletion = true
-	vaultRefPrefix     = "vault:secret/data/backup"
+	defaultSchedule          = "*/30 * * * *"
+	defaultPruneIntervalDays = 14
+	defaultCopyMethod        = volsyncv1alpha1.CopyMethodSnapshot
+	enableFileDeletion       = true
+	vaultRefPrefix           = "vault:secret/data/backup"
 )
 
+var defaultRetain = RetainSettings{
+	Hourly:  ptr.To[int32](6),
+	Daily:   ptr.To[int32](5),
+	Weekly:  ptr.To[int32](4),
+	Monthly: ptr.To[int32](2),
+	Yearly:  ptr.To[int32](1),
+}
+
+var copyMethods = []string{
+	string(volsyncv1alpha1.CopyMethodSnapshot),
+	string(volsyncv1alpha1.CopyMethodClone),
+	string(volsyncv1alpha1.CopyMethodDirect),
+}
+
 type Config struct {
 	Backup struct {
-		Volumes map[string]VolumeSettings `yaml:"volumes"`
+		// Defaults apply to every volume field that the volume does not set,
+		// and can differ per environment through settings overlays.
+		Defaults VolumeSettings            `yaml:"defaults,omitempty"`
+		Volumes  map[string]VolumeSettings `yaml:"volumes"`
 	} `yaml:"backups"`
 }
 
 type VolumeSettings struct {
-	MoverSecurityContext *corev1.PodSecurityContext `yaml:"mover_security_context,omitempty"`
+	MoverSecurityContext *PodSecurityContext `yaml:"mover_security_context,omitempty"`
+	Schedule             string              `yaml:"schedule,omitempty"`
+	Retain               *RetainSettings     `yaml:"retain,omitempty"`
+	PruneIntervalDays    *int32              `yaml:"prune_interval_days,omitempty"`
+	CopyMethod           string              `yaml:"copy_method,omitempty" enum:"Snapshot,Clone,Direct"`
+	Verify               *VerifySettings     `yaml:"verify,omitempty"`
+	Hooks                *HookSettings       `yaml:"hooks,omitempty"`
+}
+
+// HookSettings are commands run in a pod of the volume's app before and after
+// each on-demand backup, to make the backed up data consistent, for example by
+// dumping a database to the volume or flushing and locking it.
+type HookSettings struct {
+	// Selector is the label selector of the pod, of which the first running
+	// one is used.
+	Selector  string   `yaml:"selector"`
+	Container string   `yaml:"container,omitempty"`
+	Pre       []string `yaml:"pre,omitempty"`
+	Post      []string `yaml:"post,omitempty"`
+}
+
+// RetainSettings is the number of restic snapshots kept per period. A volume
+// that sets retain replaces the default policy as a whole.
+type RetainSettings struct {
+	Hourly  *int32 `yaml:"hourly,omitempty"`
+	Daily   *int32 `yaml:"daily,omitempty"`
+	Weekly  *int32 `yaml:"weekly,omitempty"`
+	Monthly *int32 `yaml:"monthly,omitempty"`
+	Yearly  *int32 `yaml:"yearly,omitempty"`
+	// Within keeps every snapshot taken within a restic duration, e.g. 3d12h.
+	Within string `yaml:"within,omitempty"`
+}
+
+// PodSecurityContext decodes a corev1.PodSecurityContext with the field names
+// used in Kubernetes manifests, e.g. runAsUser, which yaml.v3 would not match
+// against the Go field names.
+type PodSecurityContext struct {
+	corev1.PodSecurityContext
+}
+
+func (c *PodSecurityContext) UnmarshalYAML(node *yaml.Node) error {
+	data, err := yaml.Marshal(node)
+	if err != nil {
+		return err
+	}
+	return k8syaml.UnmarshalStrict(data, &c.PodSecurityContext)
+}
+
+func (c PodSecurityContext) MarshalYAML() (any, error) {
+	data, err := k8syaml.Marshal(c.PodSecurityContext)
+	if err != nil {
+		return nil, err
+	}
+	var value map[string]any
+	return value, yaml.Unmarshal(data, &value)
+}
+
+func (PodSecurityContext) JSONSchema() map[string]any {
+	return map[string]any{"type": "object", "description": "Kubernetes PodSecurityContext for the VolSync mover"}
 }
 
 type Volume struct {
 	Namespace            string
 	PVC                  string
 	MoverSecurityContext *corev1.PodSecurityContext
+	Schedule             string
+	Retain               RetainSettings
+	PruneIntervalDays    int32
+	CopyMethod           volsyncv1alpha1.CopyMethodType
+	Verify               VerifySettings
+	Hooks                *HookSettings
 }
 
 type Object interface {
@@ -47,15 +134,17 @@ type Object interface {
 
 func (v Volume) Key() string { return v.Namespace + "/" + v.PVC }
 
-func LoadConfig(path string) (*Config, error) {
-	data, err := os.ReadFile(path)
+// LoadConfig loads the backup inventory from the settings file at path
+// merged with its includes and the overlay for env.
+func LoadConfig(path, env string) (*Config, error) {
+	merged, err := settings.Load(path, env)
 	if err != nil {
-		return nil, fmt.Errorf("read file: %w", err)
+		return nil, err
 	}
 
 	var config Config
-	if err := yaml.Unmarshal(data, &config); err != nil {
-		return nil, fmt.Errorf("parse YAML: %w", err)
+	if err := merged.Decode(&config); err != nil {
+		return nil, err
 	}
 	return &config, nil
 }
@@ -65,21 +154,120 @@ func ParseAndValidate(config *Config) ([]Volume, error) {
 		return nil, fmt.Errorf("backups.volumes: at least one volume is required")
 	}
 	volumes := make([]Volume, 0, len(config.Backup.Volumes))
-	for key, settings := range config.Backup.Volumes {
+	var errs []error
+	defaults := config.Backup.Defaults
+	if err := validateVolumeSettings("backups.defaults", defaults); err != nil {
+		errs = append(errs, settings.AtKeys(err, "backups", "defaults"))
+	}
+	for _, key := range slices.Sorted(maps.Keys(config.Backup.Volumes)) {
+		volumeSettings := config.Backup.Volumes[key]
 		namespace, pvc, err := splitVolumeKey("backups.volumes", key)
+		if err == nil {
+			err = validateVolumeSettings("backups.volumes."+key, volumeSettings)
+		}
 		if err != nil {
-			return nil, err
+			errs = append(errs, settings.AtKeys(err, "backups", "volumes", key))
+			continue
 		}
-		volumes = append(volumes, Volume{
-			Namespace:            namespace,
-			PVC:                  pvc,
-			MoverSecurityContext: settings.MoverSecurityContext,
-		})
+		volumes = append(volumes, newVolume(namespace, pvc, volumeSettings.withDefaults(defaults)))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
 	}
 	slices.SortFunc(volumes, func(a, b Volume) int { return cmp.Compare(a.Key(), b.Key()) })
 	return volumes, nil
 }
 
+func validateVolumeSettings(context string, volume VolumeSettings) error {
+	if volume.Schedule != "" {
+		if err := validateSchedule(volume.Schedule); err != nil {
+			return fmt.Errorf("%s: %w", context, err)
+		}
+	}
+	if volume.PruneIntervalDays != nil && *volume.PruneIntervalDays < 1 {
+		return fmt.Errorf("%s: prune_interval_days must be at least 1", context)
+	}
+	if volume.CopyMethod != "" && !slices.Contains(copyMethods, volume.CopyMethod) {
+		return fmt.Errorf("%s: copy_method must be one of %s, got %q", context, strings.Join(copyMethods, ", "), volume.CopyMethod)
+	}
+	if volume.Retain != nil {
+		if err := validateRetain(*volume.Retain); err != nil {
+			return fmt.Errorf("%s: retain: %w", context, err)
+		}
+	}
+	if volume.Verify != nil && len(volume.Verify.Command) == 0 {
+		return fmt.Errorf("%s: verify: command is required", context)
+	}
+	if volume.Hooks != nil {
+		if volume.Hooks.Selector == "" {
+			return fmt.Errorf("%s: hooks: selector is required", context)
+		}
+		if len(volume.Hooks.Pre) == 0 && len(volume.Hooks.Post) == 0 {
+			return fmt.Errorf("%s: hooks: at least one of pre and post is required", context)
+		}
+	}
+	return nil
+}
+
+func validateRetain(retain RetainSettings) error {
+	kept := retain.Within != ""
+	for _, count := range []*int32{retain.Hourly, retain.Daily, retain.Weekly, retain.Monthly, retain.Yearly} {
+		if count == nil {
+			continue
+		}
+		if *count < 0 {
+			return fmt.Errorf("counts must be >= 0")
+		}
+		kept = kept || *count > 0
+	}
+	if retain.Within != "" && !resticDurationPattern.MatchString(retain.Within) {
+		return fmt.Errorf("within must be a restic duration such as 3d12h, got %q", retain.Within)
+	}
+	if !kept {
+		return fmt.Errorf("at least one snapshot must be kept")
+	}
+	return nil
+}
+
+// withDefaults returns the settings with every unset field taken from
+// defaults, and then from the built-in defaults.
+func (s VolumeSettings) withDefaults(defaults VolumeSettings) VolumeSettings {
+	for _, fallback := range []VolumeSettings{defaults, {
+		Schedule:          defaultSchedule,
+		Retain:            &defaultRetain,
+		PruneIntervalDays: ptr.To[int32](defaultPruneIntervalDays),
+		CopyMethod:        string(defaultCopyMethod),
+		Verify:            &defaultVerify,
+	}} {
+		s.MoverSecurityContext = cmp.Or(s.MoverSecurityContext, fallback.MoverSecurityContext)
+		s.Schedule = cmp.Or(s.Schedule, fallback.Schedule)
+		s.Retain = cmp.Or(s.Retain, fallback.Retain)
+		s.PruneIntervalDays = cmp.Or(s.PruneIntervalDays, fallback.PruneIntervalDays)
+		s.CopyMethod = cmp.Or(s.CopyMethod, fallback.CopyMethod)
+		s.Verify = cmp.Or(s.Verify, fallback.Verify)
+		s.Hooks = cmp.Or(s.Hooks, fallback.Hooks)
+	}
+	return s
+}
+
+func newVolume(namespace, pvc string, volumeSettings VolumeSettings) Volume {
+	volume := Volume{
+		Namespace:         namespace,
+		PVC:               pvc,
+		Schedule:          volumeSettings.Schedule,
+		Retain:            *volumeSettings.Retain,
+		PruneIntervalDays: *volumeSettings.PruneIntervalDays,
+		CopyMethod:        volsyncv1alpha1.CopyMethodType(volumeSettings.CopyMethod),
+		Verify:            *volumeSettings.Verify,
+		Hooks:             volumeSettings.Hooks,
+	}
+	volume.Verify.Image = cmp.Or(volume.Verify.Image, defaultVerify.Image)
+	if volumeSettings.MoverSecurityContext != nil {
+		volume.MoverSecurityContext = &volumeSettings.MoverSecurityContext.PodSecurityContext
+	}
+	return volume
+}
+
 func FilterVolumes(volumes []Volume, selectors []string) ([]Volume, error) {
 	if len(selectors) == 0 {
 		return volumes, nil
@@ -120,14 +308,100 @@ func BuildSetupObjects(volumes []Volume) []Object {
 	return objects
 }
 
-func BuildRestoreObjects(volumes []Volume, restoreTrigger string) []Object {
+// RestoreOptions selects the snapshot a restore starts from. Without
+// RestoreAsOf and Previous it is the latest snapshot.
+type RestoreOptions struct {
+	Trigger string `json:"trigger"`
+	// RestoreAsOf restores the newest snapshot taken at or before this time.
+	RestoreAsOf *time.Time `json:"restore_as_of,omitempty"`
+	// Previous skips this many snapshots, newest first, before selecting one.
+	Previous *int32 `json:"previous,omitempty"`
+}
+
+func BuildRestoreObjects(volumes []Volume, options RestoreOptions) []Object {
 	objects := make([]Object, 0, len(volumes)*2)
 	for _, volume := range volumes {
-		objects = append(objects, repositorySecret(volume), replicationDestination(volume, restoreTrigger))
+		objects = append(objects, repositorySecret(volume), replicationDestination(volume, options))
 	}
 	return objects
 }
 
+// CloneTarget is a new PVC that a volume is restored into, leaving the
+// original PVC and its workloads untouched.
+type CloneTarget struct {
+	Namespace string
+	PVC       string
+	// StorageClass is the storage class of the new PVC, or the cluster
+	// default if empty.
+	StorageClass string
+	Capacity     resource.Quantity
+}
+
+// ValidateCloneTarget checks that target is a different PVC from volume.
+func ValidateCloneTarget(volume Volume, target CloneTarget) error {
+	if _, _, err := splitVolumeKey("clone target", target.Namespace+"/"+target.PVC); err != nil {
+		return err
+	}
+	if target.Namespace == volume.Namespace && target.PVC == volume.PVC {
+		return fmt.Errorf("clone target %s/%s must differ from the volume", target.Namespace, target.PVC)
+	}
+	if target.Capacity.Sign() <= 0 {
+		return fmt.Errorf("clone target %s/%s: capacity must be positive", target.Namespace, target.PVC)
+	}
+	return nil
+}
+
+// BuildCloneObjects returns the objects that restore a snapshot of volume
+// into the new PVC described by target. The repository secret is created in
+// the target namespace but still points at the repository of volume.
+func BuildCloneObjects(volume Volume, target CloneTarget, options RestoreOptions) []Object {
+	var objects []Object
+	if target.Namespace != volume.Namespace {
+		objects = append(objects, &corev1.Namespace{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Namespace"},
+			ObjectMeta: metav1.ObjectMeta{Name: target.Namespace},
+		})
+	}
+
+	secret := repositorySecret(volume).(*corev1.Secret)
+	secret.Namespace = target.Namespace
+	secret.Name = target.PVC + "-restic-repository"
+
+	destination := replicationDestination(volume, options).(*volsyncv1alpha1.ReplicationDestination)
+	destination.Namespace = target.Namespace
+	destination.Name = CloneDestinationName(target)
+	destination.Spec.Restic.Repository = secret.Name
+	destination.Spec.Restic.CopyMethod = volsyncv1alpha1.CopyMethodSnapshot
+	destination.Spec.Restic.DestinationPVC = ptr.To(target.PVC)
+
+	return append(objects, clonePVC(target), secret, destination)
+}
+
+func CloneDestinationName(target CloneTarget) string { return target.PVC + "-restore" }
+
+func clonePVC(target CloneTarget) Object {
+	pvc := &corev1.PersistentVolumeClaim{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "PersistentVolumeClaim",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      target.PVC,
+			Namespace: target.Namespace,
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+			Resources: corev1.VolumeResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: target.Capacity},
+			},
+		},
+	}
+	if target.StorageClass != "" {
+		pvc.Spec.StorageClassName = ptr.To(target.StorageClass)
+	}
+	return pvc
+}
+
 func RenderYAML(objects []Object) ([]byte, error) {
 	var builder strings.Builder
 	for i, object := range objects {
@@ -143,6 +417,8 @@ func RenderYAML(objects []Object) ([]byte, error) {
 	return []byte(builder.String()), nil
 }
 
+func SourceName(volume Volume) string { return volume.PVC + "-backup" }
+
 func DestinationName(volume Volume) string { return volume.PVC + "-restore" }
 
 func splitVolumeKey(context, key string) (string, string, error) {
@@ -181,18 +457,21 @@ func repositorySecret(volume Volume) Object {
 func replicationSource(volume Volume) Object {
 	restic := &volsyncv1alpha1.ReplicationSourceResticSpec{
 		ReplicationSourceVolumeOptions: volsyncv1alpha1.ReplicationSourceVolumeOptions{
-			CopyMethod: volsyncv1alpha1.CopyMethodSnapshot,
+			CopyMethod: volume.CopyMethod,
 		},
 		Repository:        repositorySecretName(volume),
-		PruneIntervalDays: ptr.To[int32](pruneInterval),
+		PruneIntervalDays: ptr.To(volume.PruneIntervalDays),
 		Retain: &volsyncv1alpha1.ResticRetainPolicy{
-			Hourly:  ptr.To[int32](6),
-			Daily:   ptr.To[int32](5),
-			Weekly:  ptr.To[int32](4),
-			Monthly: ptr.To[int32](2),
-			Yearly:  ptr.To[int32](1),
+			Hourly:  volume.Retain.Hourly,
+			Daily:   volume.Retain.Daily,
+			Weekly:  volume.Retain.Weekly,
+			Monthly: volume.Retain.Monthly,
+			Yearly:  volume.Retain.Yearly,
 		},
 	}
+	if volume.Retain.Within != "" {
+		restic.Retain.Within = ptr.To(volume.Retain.Within)
+	}
 	restic.MoverSecurityContext = moverSecurityContext(volume)
 
 	return &volsyncv1alpha1.ReplicationSource{
@@ -201,20 +480,20 @@ func replicationSource(volume Volume) Object {
 			Kind:       "ReplicationSource",
 		},
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      volume.PVC + "-backup",
+			Name:      SourceName(volume),
 			Namespace: volume.Namespace,
 		},
 		Spec: volsyncv1alpha1.ReplicationSourceSpec{
 			SourcePVC: volume.PVC,
 			Trigger: &volsyncv1alpha1.ReplicationSourceTriggerSpec{
-				Schedule: ptr.To(schedule),
+				Schedule: ptr.To(volume.Schedule),
 			},
 			Restic: restic,
 		},
 	}
 }
 
-func replicationDestination(volume Volume, restoreTrigger string) Object {
+func replicationDestination(volume Volume, options RestoreOptions) Object {
 	restic := &volsyncv1alpha1.ReplicationDestinationResticSpec{
 		ReplicationDestinationVolumeOptions: volsyncv1alpha1.ReplicationDestinationVolumeOptions{
 			CopyMethod:     volsyncv1alpha1.CopyMethodDirect,
@@ -222,6 +501,10 @@ func replicationDestination(volume Volume, restoreTrigger string) Object {
 		},
 		Repository:         repositorySecretName(volume),
 		EnableFileDeletion: enableFileDeletion,
+		Previous:           options.Previous,
+	}
+	if options.RestoreAsOf != nil {
+		restic.RestoreAsOf = ptr.To(options.RestoreAsOf.UTC().Format(time.RFC3339))
 	}
 	restic.MoverSecurityContext = moverSecurityContext(volume)
 
@@ -235,7 +518,7 @@ func replicationDestination(volume Volume, restoreTrigger string) Object {
 			Namespace: volume.Namespace,
 		},
 		Spec: volsyncv1alpha1.ReplicationDestinationSpec{
-			Trigger: &volsyncv1alpha1.ReplicationDestinationTriggerSpec{Manual: restoreTrigger},
+			Trigger: &volsyncv1alpha1.ReplicationDestinationTriggerSpec{Manual: options.Trigger},
 			Restic:  restic,
 		},
 	}
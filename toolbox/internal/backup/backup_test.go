@@ -0,0 +1,117 @@
+package backup
+
+import (
+	"strings"
+	"testing"
+
+	volsyncv1alpha1 "github.com/backube/volsync/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/utils/ptr"
+)
+
+func TestParseAndValidateAppliesDefaults(t *testing.T) {
+	config := &Config{}
+	config.Backup.Defaults = VolumeSettings{Schedule: "0 * * * *", PruneIntervalDays: ptr.To[int32](7)}
+	config.Backup.Volumes = map[string]VolumeSettings{
+		"finance/actualbudget": {},
+		"forgejo/gitea-shared-storage": {
+			Schedule:   "0 3 * * *",
+			Retain:     &RetainSettings{Daily: ptr.To[int32](7), Within: "2d"},
+			CopyMethod: "Clone",
+		},
+	}
+
+	volumes, err := ParseAndValidate(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	budget, forgejo := volumes[0], volumes[1]
+	if budget.Schedule != "0 * * * *" || budget.PruneIntervalDays != 7 || budget.CopyMethod != volsyncv1alpha1.CopyMethodSnapshot || *budget.Retain.Hourly != 6 {
+		t.Errorf("expected environment and built-in defaults, got %+v", budget)
+	}
+	if forgejo.Schedule != "0 3 * * *" || forgejo.PruneIntervalDays != 7 || forgejo.CopyMethod != volsyncv1alpha1.CopyMethodClone || forgejo.Retain.Hourly != nil {
+		t.Errorf("expected volume settings to override defaults, got %+v", forgejo)
+	}
+
+	source := replicationSource(forgejo).(*volsyncv1alpha1.ReplicationSource)
+	if *source.Spec.Trigger.Schedule != "0 3 * * *" || *source.Spec.Restic.Retain.Within != "2d" || *source.Spec.Restic.Retain.Daily != 7 {
+		t.Errorf("unexpected ReplicationSource spec %+v", source.Spec)
+	}
+}
+
+func TestParseAndValidateRejectsInvalidVolumeSettings(t *testing.T) {
+	cases := []struct {
+		name     string
+		settings VolumeSettings
+		wantErr  string
+	}{
+		{"schedule with too few fields", VolumeSettings{Schedule: "* * * *"}, "must be five fields"},
+		{"schedule with names", VolumeSettings{Schedule: "0 0 * * MON"}, "must be five fields"},
+		{"negative retention", VolumeSettings{Retain: &RetainSettings{Daily: ptr.To[int32](-1)}}, "counts must be >= 0"},
+		{"empty retention", VolumeSettings{Retain: &RetainSettings{Daily: ptr.To[int32](0)}}, "at least one snapshot"},
+		{"invalid within", VolumeSettings{Retain: &RetainSettings{Within: "2 days"}}, "restic duration"},
+		{"zero prune interval", VolumeSettings{PruneIntervalDays: ptr.To[int32](0)}, "prune_interval_days"},
+		{"unknown copy method", VolumeSettings{CopyMethod: "snapshot"}, "copy_method must be one of"},
+		{"hooks without selector", VolumeSettings{Hooks: &HookSettings{Pre: []string{"sync"}}}, "hooks: selector is required"},
+		{"hooks without commands", VolumeSettings{Hooks: &HookSettings{Selector: "app=forgejo"}}, "at least one of pre and post"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := &Config{}
+			config.Backup.Volumes = map[string]VolumeSettings{"finance/actualbudget": tc.settings}
+			_, err := ParseAndValidate(config)
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("expected error %q, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateScheduleAcceptsVolSyncSyntax(t *testing.T) {
+	for _, schedule := range []string{"*/30 * * * *", "0 */6 * * *", "15 2 1,15 * 1-5", "0 0/6 * * *", "@daily", "0 0 * * 7"} {
+		if err := validateSchedule(schedule); err != nil {
+			t.Errorf("validateSchedule(%q) = %v", schedule, err)
+		}
+	}
+}
+
+func TestValidateScheduleRejectsWhatVolSyncRejects(t *testing.T) {
+	for _, schedule := range []string{"15 2 1,15 * 0-6/2", "* * * *", "0 0 * * MON", "0 0 ? * *", "@reboot"} {
+		if err := validateSchedule(schedule); err == nil {
+			t.Errorf("expected validateSchedule(%q) to fail", schedule)
+		}
+	}
+}
+
+func TestBuildCloneObjects(t *testing.T) {
+	volume := Volume{Namespace: "finance", PVC: "actualbudget"}
+	target := CloneTarget{Namespace: "scratch", PVC: "actualbudget-clone", StorageClass: "local-path", Capacity: resource.MustParse("5Gi")}
+	if err := ValidateCloneTarget(volume, target); err != nil {
+		t.Fatal(err)
+	}
+
+	objects := BuildCloneObjects(volume, target, RestoreOptions{Trigger: "restore-1"})
+	if len(objects) != 4 || objects[0].GetName() != "scratch" {
+		t.Fatalf("expected namespace, PVC, secret and ReplicationDestination, got %d objects", len(objects))
+	}
+	pvc := objects[1].(*corev1.PersistentVolumeClaim)
+	if *pvc.Spec.StorageClassName != "local-path" || pvc.Spec.Resources.Requests.Storage().String() != "5Gi" {
+		t.Errorf("unexpected clone PVC spec %+v", pvc.Spec)
+	}
+	secret := objects[2].(*corev1.Secret)
+	if secret.Namespace != "scratch" || !strings.HasSuffix(string(secret.Data["RESTIC_REPOSITORY"]), "/finance/actualbudget") {
+		t.Errorf("expected secret in scratch for the original repository, got %s/%s %s", secret.Namespace, secret.Name, secret.Data["RESTIC_REPOSITORY"])
+	}
+	destination := objects[3].(*volsyncv1alpha1.ReplicationDestination)
+	restic := destination.Spec.Restic
+	if destination.Namespace != "scratch" || restic.Repository != secret.Name || *restic.DestinationPVC != "actualbudget-clone" || restic.CopyMethod != volsyncv1alpha1.CopyMethodSnapshot {
+		t.Errorf("unexpected ReplicationDestination %s/%s %+v", destination.Namespace, destination.Name, restic)
+	}
+
+	if err := ValidateCloneTarget(volume, CloneTarget{Namespace: "finance", PVC: "actualbudget", Capacity: resource.MustParse("5Gi")}); err == nil {
+		t.Error("expected cloning onto the volume itself to fail")
+	}
+}
@@ -0,0 +1,138 @@
+package backup
+
+import (
+	"slices"
+	"strings"
+)
+
+// CNPGHibernationAnnotation hibernates a CloudNativePG cluster when set to
+// "on": its pods are deleted while its PVCs are kept.
+const CNPGHibernationAnnotation = "cnpg.io/hibernation"
+
+// CronJobsJSONPath is the kubectl output format that ParseCronJobs reads.
+const CronJobsJSONPath = `jsonpath={range .items[*]}{.metadata.name}{"\t"}{.spec.suspend}{"\t"}{.spec.jobTemplate.spec.template.spec.volumes[*].persistentVolumeClaim.claimName}{"\n"}{end}`
+
+// ClustersJSONPath is the kubectl output format that ParseClusters reads.
+const ClustersJSONPath = `jsonpath={range .items[*]}{.metadata.name}{"\t"}{.metadata.annotations.cnpg\.io/hibernation}{"\n"}{end}`
+
+// PodsJSONPath is the kubectl output format that ParsePods reads.
+const PodsJSONPath = `jsonpath={range .items[*]}{.metadata.name}{"\t"}{.metadata.ownerReferences[*].kind}{"\t"}{.spec.volumes[*].persistentVolumeClaim.claimName}{"\n"}{end}`
+
+// CronJob is a CronJob that the restore suspended.
+type CronJob struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+
+	suspended bool
+	claims    []string
+}
+
+// Cluster is a CloudNativePG cluster that the restore hibernated, with the
+// value its hibernation annotation had before.
+type Cluster struct {
+	Namespace   string `json:"namespace"`
+	Name        string `json:"name"`
+	Hibernation string `json:"hibernation,omitempty"`
+}
+
+// Pod is a pod with the kinds of its owners and the PVCs it mounts.
+type Pod struct {
+	Namespace string
+	Name      string
+	Owners    []string
+	Claims    []string
+}
+
+// ParseCronJobs parses the CronJobs of a namespace printed with
+// CronJobsJSONPath.
+func ParseCronJobs(namespace string, data []byte) []CronJob {
+	var cronJobs []CronJob
+	for _, fields := range parseFields(data, 3) {
+		cronJobs = append(cronJobs, CronJob{
+			Namespace: namespace,
+			Name:      fields[0],
+			suspended: fields[1] == "true",
+			claims:    strings.Fields(fields[2]),
+		})
+	}
+	return cronJobs
+}
+
+// ParseClusters parses the CloudNativePG clusters of a namespace printed with
+// ClustersJSONPath.
+func ParseClusters(namespace string, data []byte) []Cluster {
+	var clusters []Cluster
+	for _, fields := range parseFields(data, 2) {
+		clusters = append(clusters, Cluster{Namespace: namespace, Name: fields[0], Hibernation: fields[1]})
+	}
+	return clusters
+}
+
+// ParsePods parses the pods of a namespace printed with PodsJSONPath.
+func ParsePods(namespace string, data []byte) []Pod {
+	var pods []Pod
+	for _, fields := range parseFields(data, 3) {
+		pods = append(pods, Pod{Namespace: namespace, Name: fields[0], Owners: strings.Fields(fields[1]), Claims: strings.Fields(fields[2])})
+	}
+	return pods
+}
+
+// ActiveCronJobs returns the CronJobs that are not suspended, or only those
+// whose jobs mount one of the volumes with pvcOnly.
+func ActiveCronJobs(cronJobs []CronJob, volumes []Volume, pvcOnly bool) []CronJob {
+	return slices.DeleteFunc(slices.Clone(cronJobs), func(cronJob CronJob) bool {
+		return cronJob.suspended || pvcOnly && !mountsVolume(cronJob.Namespace, cronJob.claims, volumes)
+	})
+}
+
+// AwakeClusters returns the clusters that are not hibernated, or only those
+// owning one of the volumes with pvcOnly.
+func AwakeClusters(clusters []Cluster, volumes []Volume, pvcOnly bool) []Cluster {
+	return slices.DeleteFunc(slices.Clone(clusters), func(cluster Cluster) bool {
+		return cluster.Hibernation == "on" || pvcOnly && !slices.ContainsFunc(volumes, func(volume Volume) bool {
+			return volume.Namespace == cluster.Namespace && cluster.Owns(volume.PVC)
+		})
+	})
+}
+
+// Owns reports whether pvc is one of the cluster's instance PVCs, which
+// CloudNativePG names <cluster>-<serial> with an optional -wal or
+// -tbs-<tablespace> suffix.
+func (c Cluster) Owns(pvc string) bool {
+	serial, ok := strings.CutPrefix(pvc, c.Name+"-")
+	return ok && serial != "" && serial[0] >= '0' && serial[0] <= '9'
+}
+
+// UnmanagedPods returns the pods mounting one of the volumes that restore
+// cannot stop: bare pods without an owner, and DaemonSet pods.
+func UnmanagedPods(pods []Pod, volumes []Volume) []Pod {
+	return slices.DeleteFunc(slices.Clone(pods), func(pod Pod) bool {
+		unmanaged := len(pod.Owners) == 0 || slices.Contains(pod.Owners, "DaemonSet")
+		return !unmanaged || !mountsVolume(pod.Namespace, pod.Claims, volumes)
+	})
+}
+
+// MountingPods returns the pods that mount one of the volumes.
+func MountingPods(pods []Pod, volumes []Volume) []Pod {
+	return slices.DeleteFunc(slices.Clone(pods), func(pod Pod) bool {
+		return !mountsVolume(pod.Namespace, pod.Claims, volumes)
+	})
+}
+
+func mountsVolume(namespace string, claims []string, volumes []Volume) bool {
+	return slices.ContainsFunc(volumes, func(volume Volume) bool {
+		return volume.Namespace == namespace && slices.Contains(claims, volume.PVC)
+	})
+}
+
+// parseFields splits kubectl jsonpath output into lines of tab-separated
+// fields, skipping lines without the expected number of fields.
+func parseFields(data []byte, count int) [][]string {
+	var lines [][]string
+	for line := range strings.Lines(string(data)) {
+		if fields := strings.Split(strings.TrimRight(line, "\n"), "\t"); len(fields) == count {
+			lines = append(lines, fields)
+		}
+	}
+	return lines
+}
@@ -0,0 +1,37 @@
+package backup
+
+import (
+	"testing"
+)
+
+func TestQuiesceSelection(t *testing.T) {
+	volumes := []Volume{{Namespace: "temporal", PVC: "data"}, {Namespace: "temporal", PVC: "postgres-1"}}
+
+	cronJobs := ParseCronJobs("temporal", []byte("cleanup\t\tdata\nreport\t\t\npaused\ttrue\tdata\n"))
+	if active := ActiveCronJobs(cronJobs, volumes, false); len(active) != 2 {
+		t.Errorf("expected every unsuspended CronJob, got %+v", active)
+	}
+	if active := ActiveCronJobs(cronJobs, volumes, true); len(active) != 1 || active[0].Name != "cleanup" {
+		t.Errorf("expected only the CronJob mounting the volume, got %+v", active)
+	}
+
+	clusters := ParseClusters("temporal", []byte("postgres\t\nanalytics\toff\narchive\ton\n"))
+	if awake := AwakeClusters(clusters, volumes, false); len(awake) != 2 || awake[1].Hibernation != "off" {
+		t.Errorf("expected clusters that are not hibernated, got %+v", awake)
+	}
+	if awake := AwakeClusters(clusters, volumes, true); len(awake) != 1 || awake[0].Name != "postgres" {
+		t.Errorf("expected only the cluster owning the volume, got %+v", awake)
+	}
+	if !clusters[0].Owns("postgres-1-wal") || clusters[0].Owns("postgres-backup") {
+		t.Error("expected cluster to own only its instance PVCs")
+	}
+
+	pods := ParsePods("temporal", []byte("debug\t\tdata\nnode-agent-x\tDaemonSet\tdata\nweb-1\tReplicaSet\tdata\nshell\t\t\n"))
+	unmanaged := UnmanagedPods(pods, volumes)
+	if len(unmanaged) != 2 || unmanaged[0].Name != "debug" || unmanaged[1].Name != "node-agent-x" {
+		t.Errorf("expected bare and DaemonSet pods mounting the volume, got %+v", unmanaged)
+	}
+	if mounting := MountingPods(pods, volumes); len(mounting) != 3 {
+		t.Errorf("expected three pods mounting the volume, got %+v", mounting)
+	}
+}
@@ -0,0 +1,23 @@
+package backup
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// schedulePattern is the pattern of the schedule field in the VolSync
+// ReplicationSource CRD, so that schedules the API server rejects fail
+// validation instead of apply.
+var schedulePattern = regexp.MustCompile(`^(@(annually|yearly|monthly|weekly|daily|hourly))|((((\d+,)*\d+|(\d+(\/|-)\d+)|\*(\/\d+)?)\s?){5})$`)
+
+var resticDurationPattern = regexp.MustCompile(`^([0-9]+[yMwdh])+$`)
+
+// validateSchedule checks a cron expression against the syntax VolSync
+// accepts: five fields of numbers, lists, a single range or step, or * with an
+// optional step, or one of the @ shorthands.
+func validateSchedule(schedule string) error {
+	if !schedulePattern.MatchString(schedule) {
+		return fmt.Errorf("schedule %q must be five fields of numbers, lists, N-M, N/M or */N, or one of @yearly, @annually, @monthly, @weekly, @daily, @hourly", schedule)
+	}
+	return nil
+}
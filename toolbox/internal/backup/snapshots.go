@@ -0,0 +1,70 @@
+package backup
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Credentials are the restic password and S3 settings shared by every backup
+// repository, as stored in Vault under secret/backup.
+type Credentials struct {
+	Password        string
+	Endpoint        string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+}
+
+// Repository returns the restic repository of a volume, matching the
+// RESTIC_REPOSITORY of its repository secret.
+func (c Credentials) Repository(volume Volume) string {
+	return fmt.Sprintf("s3:%s/%s/%s/%s", c.Endpoint, c.Bucket, volume.Namespace, volume.PVC)
+}
+
+type Snapshot struct {
+	ID       string    `json:"short_id"`
+	Time     time.Time `json:"time"`
+	Hostname string    `json:"hostname"`
+	Paths    []string  `json:"paths"`
+}
+
+// ListSnapshots returns the snapshots of a volume from newest to oldest, so
+// that the index of a snapshot is the value of --previous that restores it.
+func ListSnapshots(ctx context.Context, credentials Credentials, volume Volume) ([]Snapshot, error) {
+	command := exec.CommandContext(ctx, "restic", "snapshots", "--json", "--no-lock")
+	command.Env = append(os.Environ(),
+		"RESTIC_REPOSITORY="+credentials.Repository(volume),
+		"RESTIC_PASSWORD="+credentials.Password,
+		"AWS_ACCESS_KEY_ID="+credentials.AccessKeyID,
+		"AWS_SECRET_ACCESS_KEY="+credentials.SecretAccessKey,
+	)
+	var stderr bytes.Buffer
+	command.Stderr = &stderr
+	output, err := command.Output()
+	if err != nil {
+		return nil, fmt.Errorf("restic snapshots for %s: %w (output: %s)", volume.Key(), err, strings.TrimSpace(stderr.String()))
+	}
+
+	snapshots, err := parseSnapshots(output)
+	if err != nil {
+		return nil, fmt.Errorf("parse snapshots for %s: %w", volume.Key(), err)
+	}
+	return snapshots, nil
+}
+
+func parseSnapshots(data []byte) ([]Snapshot, error) {
+	var snapshots []Snapshot
+	if err := json.Unmarshal(data, &snapshots); err != nil {
+		return nil, err
+	}
+	slices.SortStableFunc(snapshots, func(a, b Snapshot) int { return cmp.Compare(b.Time.UnixNano(), a.Time.UnixNano()) })
+	return snapshots, nil
+}
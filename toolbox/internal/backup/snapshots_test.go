@@ -0,0 +1,45 @@
+package backup
+
+import (
+	"testing"
+	"time"
+
+	volsyncv1alpha1 "github.com/backube/volsync/api/v1alpha1"
+	"k8s.io/utils/ptr"
+)
+
+func TestParseSnapshotsNewestFirst(t *testing.T) {
+	snapshots, err := parseSnapshots([]byte(`[
+  {"short_id": "aaaa", "time": "2026-01-01T00:30:00.1+07:00", "hostname": "volsync"},
+  {"short_id": "cccc", "time": "2026-01-01T12:00:00Z", "hostname": "volsync"},
+  {"short_id": "bbbb", "time": "2026-01-01T06:00:00Z", "hostname": "volsync"}
+]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []string
+	for _, snapshot := range snapshots {
+		ids = append(ids, snapshot.ID)
+	}
+	if len(ids) != 3 || ids[0] != "cccc" || ids[1] != "bbbb" || ids[2] != "aaaa" {
+		t.Errorf("expected snapshots newest first, got %v", ids)
+	}
+}
+
+func TestReplicationDestinationSelectsSnapshot(t *testing.T) {
+	restoreAsOf := time.Date(2026, 1, 2, 10, 0, 0, 0, time.FixedZone("ICT", 7*60*60))
+	object := replicationDestination(Volume{Namespace: "finance", PVC: "actualbudget"}, RestoreOptions{
+		Trigger:     "restore-1",
+		RestoreAsOf: &restoreAsOf,
+		Previous:    ptr.To[int32](2),
+	})
+
+	spec := object.(*volsyncv1alpha1.ReplicationDestination).Spec
+	if spec.Trigger.Manual != "restore-1" {
+		t.Errorf("expected manual trigger restore-1, got %q", spec.Trigger.Manual)
+	}
+	if *spec.Restic.RestoreAsOf != "2026-01-02T03:00:00Z" || *spec.Restic.Previous != 2 {
+		t.Errorf("expected restoreAsOf in UTC and previous 2, got %+v", spec.Restic)
+	}
+}
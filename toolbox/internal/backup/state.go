@@ -0,0 +1,105 @@
+package backup
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// RestoreStateAnnotation holds the RestoreState on the namespaces of an
+// in-place restore while it is in progress, so that it can be resumed or
+// aborted without the local state file.
+const RestoreStateAnnotation = "cloudlab.khuedoan.com/restore-state"
+
+// Steps of an in-place restore, in order.
+const (
+	StepSuspendFlux  = "suspend-flux"
+	StepScaleDown    = "scale-down"
+	StepWaitDetached = "wait-detached"
+	StepApply        = "apply"
+	StepWaitRestore  = "wait-restore"
+	StepScaleUp      = "scale-up"
+	StepResumeFlux   = "resume-flux"
+)
+
+const restoreStateDir = ".toolbox"
+
+var RestoreSteps = []string{StepSuspendFlux, StepScaleDown, StepWaitDetached, StepApply, StepWaitRestore, StepScaleUp, StepResumeFlux}
+
+// FluxResource is a Flux HelmRelease or Kustomization in flux-system.
+type FluxResource struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+}
+
+// RestoreState records what an in-place restore changed and how far it got.
+type RestoreState struct {
+	Env     string         `json:"env"`
+	Volumes []string       `json:"volumes"`
+	Options RestoreOptions `json:"options"`
+	// Step is the last completed step, or empty before the first one.
+	Step string `json:"step,omitempty"`
+	// Suspended are the Flux resources that the restore suspended, which
+	// excludes resources that were already suspended.
+	Suspended []FluxResource `json:"suspended,omitempty"`
+	// PVCWorkloadsOnly limits scaling to workloads that mount the restored
+	// PVCs instead of every workload in their namespaces.
+	PVCWorkloadsOnly bool       `json:"pvc_workloads_only,omitempty"`
+	Workloads        []Workload `json:"workloads,omitempty"`
+	// CronJobs and Clusters are the CronJobs the restore suspended and the
+	// CloudNativePG clusters it hibernated.
+	CronJobs []CronJob `json:"cron_jobs,omitempty"`
+	Clusters []Cluster `json:"clusters,omitempty"`
+}
+
+// RemainingSteps returns the steps after the last completed one.
+func (s RestoreState) RemainingSteps() []string {
+	return RestoreSteps[slices.Index(RestoreSteps, s.Step)+1:]
+}
+
+// Reached reports whether step has completed.
+func (s RestoreState) Reached(step string) bool {
+	return slices.Index(RestoreSteps, s.Step) >= slices.Index(RestoreSteps, step)
+}
+
+// RestoreStatePath returns the default local state file of an environment.
+func RestoreStatePath(env string) string {
+	return filepath.Join(restoreStateDir, "restore-"+env+".json")
+}
+
+// LoadRestoreState reads a state file, returning nil if it does not exist.
+func LoadRestoreState(path string) (*RestoreState, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ParseRestoreState(data)
+}
+
+func ParseRestoreState(data []byte) (*RestoreState, error) {
+	var state RestoreState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("parse restore state: %w", err)
+	}
+	if state.Step != "" && !slices.Contains(RestoreSteps, state.Step) {
+		return nil, fmt.Errorf("parse restore state: unknown step %q", state.Step)
+	}
+	return &state, nil
+}
+
+func (s RestoreState) Save(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
@@ -0,0 +1,53 @@
+package backup
+
+import (
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"k8s.io/utils/ptr"
+)
+
+func TestRestoreStateRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "restore-staging.json")
+	if state, err := LoadRestoreState(path); err != nil || state != nil {
+		t.Fatalf("expected no state before saving, got %+v, %v", state, err)
+	}
+
+	restoreAsOf := time.Date(2026, 1, 2, 3, 0, 0, 0, time.UTC)
+	want := RestoreState{
+		Env:       "staging",
+		Volumes:   []string{"finance/actualbudget"},
+		Options:   RestoreOptions{Trigger: "restore-1", RestoreAsOf: &restoreAsOf, Previous: ptr.To[int32](1)},
+		Step:      StepScaleDown,
+		Suspended: []FluxResource{{Kind: "helmrelease", Name: "finance"}},
+		Workloads: []Workload{{Namespace: "finance", Kind: "deployment", Name: "actualbudget", Replicas: 3, HPA: &Autoscaler{Name: "actualbudget", MinReplicas: 2, MaxReplicas: 5}}},
+	}
+	if err := want.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	got, err := LoadRestoreState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Options.Trigger != "restore-1" || !got.Options.RestoreAsOf.Equal(restoreAsOf) || *got.Options.Previous != 1 ||
+		!slices.Equal(got.Suspended, want.Suspended) || !reflect.DeepEqual(got.Workloads, want.Workloads) {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+
+	if remaining := got.RemainingSteps(); remaining[0] != StepWaitDetached || len(remaining) != len(RestoreSteps)-2 {
+		t.Errorf("unexpected remaining steps %v", remaining)
+	}
+	if !got.Reached(StepSuspendFlux) || !got.Reached(StepScaleDown) || got.Reached(StepWaitDetached) {
+		t.Errorf("unexpected reached steps after %s", got.Step)
+	}
+	if steps := (RestoreState{}).RemainingSteps(); !slices.Equal(steps, RestoreSteps) {
+		t.Errorf("expected every step for a new restore, got %v", steps)
+	}
+
+	if _, err := ParseRestoreState([]byte(`{"step": "unknown"}`)); err == nil {
+		t.Error("expected unknown step to fail")
+	}
+}
@@ -0,0 +1,69 @@
+package backup
+
+import (
+	"fmt"
+	"time"
+
+	volsyncv1alpha1 "github.com/backube/volsync/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Status is the backup health of a volume as reported by its
+// ReplicationSource.
+type Status struct {
+	Volume           Volume
+	Found            bool
+	LastSyncTime     *time.Time
+	LastSyncDuration time.Duration
+	NextSyncTime     *time.Time
+	Result           string
+	Conditions       []metav1.Condition
+	// Problems explains why the volume is unhealthy, and is empty otherwise.
+	Problems []string
+}
+
+func (s Status) Healthy() bool { return len(s.Problems) == 0 }
+
+// SourceStatus evaluates the ReplicationSource of a volume, or nil if it does
+// not exist. A volume is stale when its last successful sync is older than
+// maxAge at now.
+func SourceStatus(volume Volume, source *volsyncv1alpha1.ReplicationSource, now time.Time, maxAge time.Duration) Status {
+	status := Status{Volume: volume}
+	if source == nil {
+		status.Problems = append(status.Problems, fmt.Sprintf("ReplicationSource %s not found, run backup setup", SourceName(volume)))
+		return status
+	}
+	status.Found = true
+
+	if sourceStatus := source.Status; sourceStatus != nil {
+		status.Conditions = sourceStatus.Conditions
+		if sourceStatus.LastSyncTime != nil {
+			status.LastSyncTime = &sourceStatus.LastSyncTime.Time
+		}
+		if sourceStatus.LastSyncDuration != nil {
+			status.LastSyncDuration = sourceStatus.LastSyncDuration.Duration
+		}
+		if sourceStatus.NextSyncTime != nil {
+			status.NextSyncTime = &sourceStatus.NextSyncTime.Time
+		}
+		if sourceStatus.LatestMoverStatus != nil {
+			status.Result = string(sourceStatus.LatestMoverStatus.Result)
+		}
+	}
+
+	switch {
+	case status.LastSyncTime == nil:
+		status.Problems = append(status.Problems, "no successful sync yet")
+	case now.Sub(*status.LastSyncTime) > maxAge:
+		status.Problems = append(status.Problems, fmt.Sprintf("last successful sync was %s ago, more than %s", now.Sub(*status.LastSyncTime).Round(time.Minute), maxAge))
+	}
+	if status.Result == string(volsyncv1alpha1.MoverResultFailed) {
+		status.Problems = append(status.Problems, "latest backup failed")
+	}
+	for _, condition := range status.Conditions {
+		if condition.Type == volsyncv1alpha1.ConditionSynchronizing && condition.Reason == volsyncv1alpha1.SynchronizingReasonError {
+			status.Problems = append(status.Problems, "synchronizing: "+condition.Message)
+		}
+	}
+	return status
+}
@@ -0,0 +1,48 @@
+package backup
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	volsyncv1alpha1 "github.com/backube/volsync/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSourceStatus(t *testing.T) {
+	now := time.Date(2026, 1, 2, 12, 0, 0, 0, time.UTC)
+	volume := Volume{Namespace: "finance", PVC: "actualbudget"}
+	source := func(lastSync time.Duration, result volsyncv1alpha1.MoverResult, reason string) *volsyncv1alpha1.ReplicationSource {
+		return &volsyncv1alpha1.ReplicationSource{Status: &volsyncv1alpha1.ReplicationSourceStatus{
+			LastSyncTime:      &metav1.Time{Time: now.Add(-lastSync)},
+			LatestMoverStatus: &volsyncv1alpha1.MoverStatus{Result: result},
+			Conditions:        []metav1.Condition{{Type: volsyncv1alpha1.ConditionSynchronizing, Reason: reason, Message: "mover failed"}},
+		}}
+	}
+
+	cases := []struct {
+		name    string
+		source  *volsyncv1alpha1.ReplicationSource
+		problem string
+	}{
+		{"healthy", source(time.Hour, volsyncv1alpha1.MoverResultSuccessful, volsyncv1alpha1.SynchronizingReasonSched), ""},
+		{"missing", nil, "not found"},
+		{"never synced", &volsyncv1alpha1.ReplicationSource{}, "no successful sync"},
+		{"stale", source(25*time.Hour, volsyncv1alpha1.MoverResultSuccessful, volsyncv1alpha1.SynchronizingReasonSched), "25h0m0s ago"},
+		{"failed", source(time.Hour, volsyncv1alpha1.MoverResultFailed, volsyncv1alpha1.SynchronizingReasonSched), "latest backup failed"},
+		{"error condition", source(time.Hour, volsyncv1alpha1.MoverResultSuccessful, volsyncv1alpha1.SynchronizingReasonError), "mover failed"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			status := SourceStatus(volume, tc.source, now, 24*time.Hour)
+			problems := strings.Join(status.Problems, "; ")
+			if tc.problem == "" && !status.Healthy() {
+				t.Fatalf("expected healthy status, got %q", problems)
+			}
+			if tc.problem != "" && !strings.Contains(problems, tc.problem) {
+				t.Fatalf("expected problem containing %q, got %q", tc.problem, problems)
+			}
+		})
+	}
+}
@@ -0,0 +1,219 @@
+package backup
+
+import (
+	"fmt"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+)
+
+const (
+	// VerifyMountPath is where check pods mount the restored data.
+	VerifyMountPath = "/data"
+	// VerifyManagedBy is the app.kubernetes.io/managed-by label of scratch
+	// namespaces created by backup verify, which are the only namespaces it
+	// deletes.
+	VerifyManagedBy    = "toolbox-backup-verify"
+	verifySettingsFile = "settings.yaml"
+)
+
+// VerifySettings is the check pod that backup verify runs against a restored
+// copy of a volume mounted read-only at /data. The check passes when the
+// command exits 0.
+type VerifySettings struct {
+	Image   string   `yaml:"image,omitempty"`
+	Command []string `yaml:"command,omitempty"`
+}
+
+var defaultVerify = VerifySettings{
+	Image:   "docker.io/library/busybox:1.37",
+	Command: []string{"sh", "-c", `count=$(find ` + VerifyMountPath + ` -type f | wc -l); echo "$count files restored"; test "$count" -gt 0`},
+}
+
+// VerifyTarget is the clone that backup verify restores a volume into. The
+// namespace is included in the PVC name so that volumes with the same PVC
+// name do not collide in the scratch namespace.
+func VerifyTarget(volume Volume, namespace string) CloneTarget {
+	return CloneTarget{Namespace: namespace, PVC: volume.Namespace + "-" + volume.PVC}
+}
+
+func VerifyNamespace(name string) Object {
+	return &corev1.Namespace{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Namespace"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: map[string]string{"app.kubernetes.io/managed-by": VerifyManagedBy},
+			// Restores run with the mover security context of each volume,
+			// which may need privileged movers like in the volume's namespace.
+			Annotations: map[string]string{"volsync.backube/privileged-movers": "true"},
+		},
+	}
+}
+
+// CheckScratchNamespace refuses a scratch namespace that holds one of the
+// volumes, or that exists without the managed-by label of backup verify,
+// since backup verify deletes it afterwards.
+func CheckScratchNamespace(namespace string, exists bool, managedBy string, volumes []Volume) error {
+	for _, volume := range volumes {
+		if volume.Namespace == namespace {
+			return fmt.Errorf("namespace %s contains volume %s; choose another --namespace", namespace, volume.Key())
+		}
+	}
+	if exists && managedBy != VerifyManagedBy {
+		return fmt.Errorf("namespace %s exists and is not managed by backup verify; choose another --namespace", namespace)
+	}
+	return nil
+}
+
+func VerifyPodName(target CloneTarget) string { return target.PVC + "-verify" }
+
+// BuildVerifyPod returns the check pod of a volume restored into target.
+func BuildVerifyPod(volume Volume, target CloneTarget) Object {
+	return &corev1.Pod{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      VerifyPodName(target),
+			Namespace: target.Namespace,
+		},
+		Spec: corev1.PodSpec{
+			RestartPolicy:   corev1.RestartPolicyNever,
+			SecurityContext: moverSecurityContext(volume),
+			Containers: []corev1.Container{{
+				Name:    "verify",
+				Image:   volume.Verify.Image,
+				Command: volume.Verify.Command,
+				VolumeMounts: []corev1.VolumeMount{{
+					Name:      "data",
+					MountPath: VerifyMountPath,
+					ReadOnly:  true,
+				}},
+			}},
+			Volumes: []corev1.Volume{{
+				Name: "data",
+				VolumeSource: corev1.VolumeSource{
+					PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: target.PVC, ReadOnly: true},
+				},
+			}},
+		},
+	}
+}
+
+// VerifyCronJobOptions configures the CronJob that runs backup verify
+// in-cluster on a schedule.
+type VerifyCronJobOptions struct {
+	// Namespace is where the CronJob runs, which must differ from the scratch
+	// namespace that verify deletes afterwards.
+	Namespace        string
+	Schedule         string
+	Image            string
+	Env              string
+	ScratchNamespace string
+	Volumes          []string
+	// Settings is the backup inventory that verify reads, already merged
+	// with its includes and the overlay for Env.
+	Settings []byte
+}
+
+// BuildVerifyCronJob returns a CronJob running backup verify with the
+// service account and permissions it needs. The scratch namespace is part of
+// the manifest, so that verify empties it instead of deleting it and most
+// permissions are limited to it. The image must contain toolbox and kubectl.
+func BuildVerifyCronJob(options VerifyCronJobOptions) ([]Object, error) {
+	if err := validateSchedule(options.Schedule); err != nil {
+		return nil, fmt.Errorf("schedule: %w", err)
+	}
+	if options.Namespace == options.ScratchNamespace {
+		return nil, fmt.Errorf("CronJob namespace %s must differ from the scratch namespace", options.Namespace)
+	}
+
+	const name = "backup-verify"
+	args := []string{"backup", "verify", "--env", options.Env, "--settings", "/etc/toolbox/" + verifySettingsFile, "--namespace", options.ScratchNamespace}
+	for _, volume := range options.Volumes {
+		args = append(args, "--volume", volume)
+	}
+	objectMeta := metav1.ObjectMeta{Name: name, Namespace: options.Namespace}
+	scratchMeta := metav1.ObjectMeta{Name: name, Namespace: options.ScratchNamespace}
+	subject := rbacv1.Subject{Kind: rbacv1.ServiceAccountKind, Name: name, Namespace: options.Namespace}
+
+	return []Object{
+		&corev1.ServiceAccount{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ServiceAccount"},
+			ObjectMeta: objectMeta,
+		},
+		// Reading the capacity of the volumes' PVCs is the only access
+		// outside of the scratch namespace.
+		&rbacv1.ClusterRole{
+			TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "ClusterRole"},
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Rules: []rbacv1.PolicyRule{
+				{APIGroups: []string{""}, Resources: []string{"namespaces"}, ResourceNames: []string{options.ScratchNamespace}, Verbs: []string{"get", "patch"}},
+				{APIGroups: []string{""}, Resources: []string{"persistentvolumeclaims"}, Verbs: []string{"get"}},
+			},
+		},
+		&rbacv1.ClusterRoleBinding{
+			TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "ClusterRoleBinding"},
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: name},
+			Subjects:   []rbacv1.Subject{subject},
+		},
+		VerifyNamespace(options.ScratchNamespace),
+		&rbacv1.Role{
+			TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "Role"},
+			ObjectMeta: scratchMeta,
+			Rules: []rbacv1.PolicyRule{
+				{APIGroups: []string{""}, Resources: []string{"persistentvolumeclaims", "secrets", "pods"}, Verbs: []string{"get", "list", "watch", "create", "patch", "delete"}},
+				{APIGroups: []string{""}, Resources: []string{"pods/log"}, Verbs: []string{"get"}},
+				{APIGroups: []string{"volsync.backube"}, Resources: []string{"replicationdestinations"}, Verbs: []string{"get", "list", "watch", "create", "patch", "delete"}},
+			},
+		},
+		&rbacv1.RoleBinding{
+			TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "RoleBinding"},
+			ObjectMeta: scratchMeta,
+			RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "Role", Name: name},
+			Subjects:   []rbacv1.Subject{subject},
+		},
+		&corev1.ConfigMap{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+			ObjectMeta: objectMeta,
+			Data:       map[string]string{verifySettingsFile: string(options.Settings)},
+		},
+		&batchv1.CronJob{
+			TypeMeta:   metav1.TypeMeta{APIVersion: batchv1.SchemeGroupVersion.String(), Kind: "CronJob"},
+			ObjectMeta: objectMeta,
+			Spec: batchv1.CronJobSpec{
+				Schedule:          options.Schedule,
+				ConcurrencyPolicy: batchv1.ForbidConcurrent,
+				JobTemplate: batchv1.JobTemplateSpec{
+					Spec: batchv1.JobSpec{
+						BackoffLimit: ptr.To[int32](0),
+						Template: corev1.PodTemplateSpec{
+							Spec: corev1.PodSpec{
+								ServiceAccountName: name,
+								RestartPolicy:      corev1.RestartPolicyNever,
+								Containers: []corev1.Container{{
+									Name:  "verify",
+									Image: options.Image,
+									Args:  args,
+									VolumeMounts: []corev1.VolumeMount{{
+										Name:      "settings",
+										MountPath: "/etc/toolbox",
+										ReadOnly:  true,
+									}},
+								}},
+								Volumes: []corev1.Volume{{
+									Name: "settings",
+									VolumeSource: corev1.VolumeSource{
+										ConfigMap: &corev1.ConfigMapVolumeSource{LocalObjectReference: corev1.LocalObjectReference{Name: name}},
+									},
+								}},
+							},
+						},
+					},
+				},
+			},
+		},
+	}, nil
+}
@@ -0,0 +1,137 @@
+package backup
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+)
+
+func TestVerifySettings(t *testing.T) {
+	var config Config
+	if err := yaml.Unmarshal([]byte(`backups:
+  volumes:
+    finance/actualbudget:
+      mover_security_context:
+        runAsUser: 1000
+      verify:
+        image: docker.io/keinos/sqlite3:3.46.1
+        command: [sqlite3, /data/server-files/account.sqlite, "PRAGMA integrity_check"]
+    forgejo/gitea-shared-storage: {}
+`), &config); err != nil {
+		t.Fatal(err)
+	}
+	volumes, err := ParseAndValidate(&config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	budget, forgejo := volumes[0], volumes[1]
+	if budget.Verify.Image != "docker.io/keinos/sqlite3:3.46.1" || budget.Verify.Command[0] != "sqlite3" {
+		t.Errorf("expected configured check, got %+v", budget.Verify)
+	}
+	if forgejo.Verify.Image != defaultVerify.Image || !slices.Equal(forgejo.Verify.Command, defaultVerify.Command) {
+		t.Errorf("expected default check, got %+v", forgejo.Verify)
+	}
+
+	target := VerifyTarget(budget, "backup-verify")
+	pod := BuildVerifyPod(budget, target).(*corev1.Pod)
+	if pod.Namespace != "backup-verify" || pod.Spec.Volumes[0].PersistentVolumeClaim.ClaimName != "finance-actualbudget" || *pod.Spec.SecurityContext.RunAsUser != 1000 {
+		t.Errorf("unexpected check pod %+v", pod)
+	}
+
+	// The inventory rendered into the CronJob must keep the field names that
+	// mover_security_context is read with.
+	data, err := yaml.Marshal(&config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "runAsUser: 1000") {
+		t.Errorf("expected Kubernetes field names in rendered settings, got:\n%s", data)
+	}
+
+	config.Backup.Volumes["forgejo/gitea-shared-storage"] = VolumeSettings{Verify: &VerifySettings{Image: "busybox"}}
+	if _, err := ParseAndValidate(&config); err == nil || !strings.Contains(err.Error(), "verify: command is required") {
+		t.Errorf("expected missing verify command error, got %v", err)
+	}
+}
+
+func TestCheckScratchNamespace(t *testing.T) {
+	volumes := []Volume{{Namespace: "forgejo", PVC: "gitea-shared-storage"}}
+	cases := []struct {
+		name      string
+		namespace string
+		exists    bool
+		managedBy string
+		wantErr   string
+	}{
+		{"missing", "backup-verify", false, "", ""},
+		{"created by verify", "backup-verify", true, VerifyManagedBy, ""},
+		{"exists without the label", "backup-verify", true, "", "not managed by backup verify"},
+		{"managed by something else", "backup-verify", true, "Helm", "not managed by backup verify"},
+		{"volume namespace", "forgejo", false, "", "contains volume forgejo/gitea-shared-storage"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := CheckScratchNamespace(tc.namespace, tc.exists, tc.managedBy, volumes)
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("expected error %q, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestBuildVerifyCronJob(t *testing.T) {
+	options := VerifyCronJobOptions{
+		Namespace:        "volsync-system",
+		Schedule:         "0 4 * * 0",
+		Image:            "toolbox:latest",
+		Env:              "production",
+		ScratchNamespace: "backup-verify",
+		Volumes:          []string{"finance/actualbudget"},
+		Settings:         []byte("backups: {}\n"),
+	}
+	objects, err := BuildVerifyCronJob(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cronJob := objects[len(objects)-1].(*batchv1.CronJob)
+	args := strings.Join(cronJob.Spec.JobTemplate.Spec.Template.Spec.Containers[0].Args, " ")
+	if args != "backup verify --env production --settings /etc/toolbox/settings.yaml --namespace backup-verify --volume finance/actualbudget" {
+		t.Errorf("unexpected CronJob args %q", args)
+	}
+
+	for _, object := range objects {
+		switch object := object.(type) {
+		case *rbacv1.ClusterRole:
+			for _, rule := range object.Rules {
+				if slices.Contains(rule.Resources, "secrets") || slices.Contains(rule.Verbs, "delete") {
+					t.Errorf("ClusterRole grants %v on %v cluster-wide", rule.Verbs, rule.Resources)
+				}
+				if slices.Contains(rule.Resources, "namespaces") && !slices.Equal(rule.ResourceNames, []string{"backup-verify"}) {
+					t.Errorf("ClusterRole grants namespaces beyond the scratch namespace: %v", rule.ResourceNames)
+				}
+			}
+		case *rbacv1.Role:
+			if object.Namespace != "backup-verify" {
+				t.Errorf("expected the Role in the scratch namespace, got %s", object.Namespace)
+			}
+		}
+	}
+
+	options.ScratchNamespace = options.Namespace
+	if _, err := BuildVerifyCronJob(options); err == nil {
+		t.Error("expected CronJob in the scratch namespace to fail")
+	}
+}
@@ -0,0 +1,112 @@
+package backup
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// WorkloadsJSONPath is the kubectl output format that ParseWorkloads reads
+// from a list of Deployments and StatefulSets.
+const WorkloadsJSONPath = `jsonpath={range .items[*]}{.kind}{"\t"}{.metadata.name}{"\t"}{.spec.replicas}{"\t"}{.spec.template.spec.volumes[*].persistentVolumeClaim.claimName}{"\t"}{.spec.volumeClaimTemplates[*].metadata.name}{"\n"}{end}`
+
+// AutoscalersJSONPath is the kubectl output format that AttachAutoscalers
+// reads from a list of HorizontalPodAutoscalers.
+const AutoscalersJSONPath = `jsonpath={range .items[*]}{.metadata.name}{"\t"}{.spec.scaleTargetRef.kind}{"\t"}{.spec.scaleTargetRef.name}{"\t"}{.spec.minReplicas}{"\t"}{.spec.maxReplicas}{"\n"}{end}`
+
+// Workload is a Deployment or StatefulSet and its replica count before the
+// restore scaled it down.
+type Workload struct {
+	Namespace string      `json:"namespace"`
+	Kind      string      `json:"kind"`
+	Name      string      `json:"name"`
+	Replicas  int32       `json:"replicas"`
+	HPA       *Autoscaler `json:"hpa,omitempty"`
+
+	claims         []string
+	claimTemplates []string
+}
+
+// Autoscaler is the HorizontalPodAutoscaler of a workload and its bounds.
+type Autoscaler struct {
+	Name        string `json:"name"`
+	MinReplicas int32  `json:"min_replicas"`
+	MaxReplicas int32  `json:"max_replicas"`
+}
+
+// ParseWorkloads parses the workloads of a namespace printed with
+// WorkloadsJSONPath.
+func ParseWorkloads(namespace string, data []byte) ([]Workload, error) {
+	var workloads []Workload
+	for _, fields := range parseFields(data, 5) {
+		replicas, err := parseReplicas(fields[2], 1)
+		if err != nil {
+			return nil, fmt.Errorf("parse replicas of %s %s/%s: %w", fields[0], namespace, fields[1], err)
+		}
+		workloads = append(workloads, Workload{
+			Namespace:      namespace,
+			Kind:           strings.ToLower(fields[0]),
+			Name:           fields[1],
+			Replicas:       replicas,
+			claims:         strings.Fields(fields[3]),
+			claimTemplates: strings.Fields(fields[4]),
+		})
+	}
+	return workloads, nil
+}
+
+// AttachAutoscalers records the bounds of the HorizontalPodAutoscalers,
+// printed with AutoscalersJSONPath, on the workloads they scale.
+func AttachAutoscalers(workloads []Workload, data []byte) error {
+	for _, fields := range parseFields(data, 5) {
+		minReplicas, err := parseReplicas(fields[3], 1)
+		if err != nil {
+			return fmt.Errorf("parse minReplicas of HorizontalPodAutoscaler %s: %w", fields[0], err)
+		}
+		maxReplicas, err := parseReplicas(fields[4], 0)
+		if err != nil {
+			return fmt.Errorf("parse maxReplicas of HorizontalPodAutoscaler %s: %w", fields[0], err)
+		}
+		for i, workload := range workloads {
+			if strings.EqualFold(workload.Kind, fields[1]) && workload.Name == fields[2] {
+				workloads[i].HPA = &Autoscaler{Name: fields[0], MinReplicas: minReplicas, MaxReplicas: maxReplicas}
+			}
+		}
+	}
+	return nil
+}
+
+// Mounts reports whether the workload's pods mount pvc, either directly or
+// through a StatefulSet volume claim template.
+func (w Workload) Mounts(pvc string) bool {
+	if slices.Contains(w.claims, pvc) {
+		return true
+	}
+	for _, template := range w.claimTemplates {
+		ordinal, ok := strings.CutPrefix(pvc, template+"-"+w.Name+"-")
+		if _, err := strconv.ParseUint(ordinal, 10, 32); ok && err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// MountingWorkloads returns the workloads that mount one of the volumes.
+func MountingWorkloads(workloads []Workload, volumes []Volume) []Workload {
+	return slices.DeleteFunc(slices.Clone(workloads), func(workload Workload) bool {
+		return !slices.ContainsFunc(volumes, func(volume Volume) bool {
+			return volume.Namespace == workload.Namespace && workload.Mounts(volume.PVC)
+		})
+	})
+}
+
+// parseReplicas parses a replica count, which kubectl prints empty when the
+// field is unset and the API server default applies.
+func parseReplicas(value string, unset int32) (int32, error) {
+	if value == "" {
+		return unset, nil
+	}
+	replicas, err := strconv.ParseInt(value, 10, 32)
+	return int32(replicas), err
+}
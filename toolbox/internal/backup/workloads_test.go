@@ -0,0 +1,36 @@
+package backup
+
+import (
+	"testing"
+)
+
+func TestParseWorkloads(t *testing.T) {
+	workloads, err := ParseWorkloads("forgejo", []byte("Deployment\tforgejo\t3\tgitea-shared-storage config\t\n"+
+		"Deployment\tworker\t0\t\t\n"+
+		"StatefulSet\tvalkey\t\t\tdata\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(workloads) != 3 || workloads[0].Replicas != 3 || workloads[1].Replicas != 0 || workloads[2].Replicas != 1 || workloads[2].Kind != "statefulset" {
+		t.Fatalf("unexpected workloads %+v", workloads)
+	}
+
+	if err := AttachAutoscalers(workloads, []byte("forgejo\tDeployment\tforgejo\t2\t5\n")); err != nil {
+		t.Fatal(err)
+	}
+	if hpa := workloads[0].HPA; hpa == nil || hpa.MinReplicas != 2 || hpa.MaxReplicas != 5 || workloads[1].HPA != nil {
+		t.Errorf("expected autoscaler bounds on forgejo only, got %+v", workloads)
+	}
+
+	mounting := MountingWorkloads(workloads, []Volume{
+		{Namespace: "forgejo", PVC: "gitea-shared-storage"},
+		{Namespace: "forgejo", PVC: "data-valkey-0"},
+		{Namespace: "other", PVC: "config"},
+	})
+	if len(mounting) != 2 || mounting[0].Name != "forgejo" || mounting[1].Name != "valkey" {
+		t.Errorf("expected forgejo and valkey to mount the volumes, got %+v", mounting)
+	}
+	if workloads[2].Mounts("data-valkey-extra") {
+		t.Error("expected claim template match to require an ordinal")
+	}
+}
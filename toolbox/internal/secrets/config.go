@@ -1,11 +1,11 @@
 package secrets
 
 import (
+	"errors"
 	"fmt"
-	"os"
 	"sort"
 
-	"gopkg.in/yaml.v3"
+	"github.com/khuedoan/cloudlab/toolbox/internal/settings"
 )
 
 // Config is the root configuration structure.
@@ -21,7 +21,7 @@ type Config struct {
 }
 
 type SecretSettings struct {
-	Type        string `yaml:"type"`
+	Type        string `yaml:"type" enum:"random,ssh,manual"`
 	Length      int    `yaml:"length,omitempty"`
 	Algorithm   string `yaml:"algorithm,omitempty"`
 	PublicKey   string `yaml:"public_key,omitempty"`
@@ -34,15 +34,17 @@ type Entry struct {
 	Settings SecretSettings
 }
 
-func LoadConfig(path string) (*Config, error) {
-	data, err := os.ReadFile(path)
+// LoadConfig loads the secrets from the settings file at path merged with
+// its includes and the overlay for env.
+func LoadConfig(path, env string) (*Config, error) {
+	merged, err := settings.Load(path, env)
 	if err != nil {
-		return nil, fmt.Errorf("read file: %w", err)
+		return nil, err
 	}
 
 	var config Config
-	if err := yaml.Unmarshal(data, &config); err != nil {
-		return nil, fmt.Errorf("parse YAML: %w", err)
+	if err := merged.Decode(&config); err != nil {
+		return nil, err
 	}
 
 	return &config, nil
@@ -50,6 +52,7 @@ func LoadConfig(path string) (*Config, error) {
 
 func ParseAndValidate(config *Config) ([]Entry, error) {
 	var entries []Entry
+	var errs []error
 
 	paths := make([]string, 0, len(config.Secrets))
 	for path := range config.Secrets {
@@ -67,19 +70,23 @@ func ParseAndValidate(config *Config) ([]Entry, error) {
 		sort.Strings(dataKeys)
 
 		for _, dataKey := range dataKeys {
-			settings := keys[dataKey]
-			if err := validateSettings(path, dataKey, settings); err != nil {
-				return nil, err
+			secretSettings := keys[dataKey]
+			if err := validateSettings(path, dataKey, secretSettings); err != nil {
+				errs = append(errs, settings.AtKeys(err, "secrets", path, dataKey))
+				continue
 			}
 
 			entries = append(entries, Entry{
 				Path:     path,
 				DataKey:  dataKey,
-				Settings: settings,
+				Settings: secretSettings,
 			})
 		}
 	}
 
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
 	return entries, nil
 }
 
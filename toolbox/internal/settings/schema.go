@@ -0,0 +1,193 @@
+package settings
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SchemaProvider is implemented by types that decode themselves and so
+// cannot be described from their fields.
+type SchemaProvider interface {
+	JSONSchema() map[string]any
+}
+
+var (
+	unmarshalerType     = reflect.TypeFor[yaml.Unmarshaler]()
+	schemaProviderType  = reflect.TypeFor[SchemaProvider]()
+	schemaSpecification = "https://json-schema.org/draft/2020-12/schema"
+)
+
+// Schema returns a JSON Schema for settings files that decode into v, which
+// is a pointer to a struct. The include and app_metadata keys are allowed in
+// every file.
+func Schema(v any) map[string]any {
+	schema := typeSchema(reflect.TypeOf(v))
+	schema["$schema"] = schemaSpecification
+	schema["title"] = "toolbox settings"
+	properties := schema["properties"].(map[string]any)
+	properties[IncludeKey] = patternsSchema("Settings files merged before this one, as glob patterns relative to it")
+	properties[AppMetadataKey] = patternsSchema("Application metadata files declaring secrets, backups and vendors, as glob patterns relative to this file")
+	return schema
+}
+
+func patternsSchema(description string) map[string]any {
+	return map[string]any{
+		"description": description,
+		"oneOf": []any{
+			map[string]any{"type": "string"},
+			map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+		},
+	}
+}
+
+func typeSchema(t reflect.Type) map[string]any {
+	if reflect.PointerTo(t).Implements(schemaProviderType) {
+		return reflect.New(t).Interface().(SchemaProvider).JSONSchema()
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return typeSchema(t.Elem())
+	case reflect.Struct:
+		properties := map[string]any{}
+		for _, field := range structFields(t) {
+			schema := typeSchema(field.Type)
+			if enum := field.Tag.Get("enum"); enum != "" {
+				schema["enum"] = strings.Split(enum, ",")
+			}
+			properties[fieldName(field)] = schema
+		}
+		return map[string]any{"type": "object", "properties": properties, "additionalProperties": false}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": typeSchema(t.Elem())}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": typeSchema(t.Elem())}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	default:
+		return map[string]any{}
+	}
+}
+
+// structFields returns the fields of a struct as yaml.v3 decodes them, with
+// inline structs flattened.
+func structFields(t reflect.Type) []reflect.StructField {
+	var fields []reflect.StructField
+	for i := range t.NumField() {
+		field := t.Field(i)
+		tag := field.Tag.Get("yaml")
+		switch {
+		case !field.IsExported() || tag == "-":
+			continue
+		case strings.Contains(tag, ",inline"):
+			fields = append(fields, structFields(field.Type)...)
+		default:
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+func fieldName(field reflect.StructField) string {
+	if name, _, _ := strings.Cut(field.Tag.Get("yaml"), ","); name != "" {
+		return name
+	}
+	return strings.ToLower(field.Name)
+}
+
+// Check reports every value of the merged document that does not decode
+// strictly into v: unknown fields and values of the wrong type. Each error
+// starts with the file and line of the value.
+func (s *Settings) Check(v any) []error {
+	checker := checker{settings: s}
+	checker.check(s.root, reflect.TypeOf(v), nil, s.sources[s.root])
+	return checker.errs
+}
+
+type checker struct {
+	settings *Settings
+	errs     []error
+}
+
+func (c *checker) errorf(node *yaml.Node, file string, keys []string, format string, args ...any) {
+	message := fmt.Sprintf(format, args...)
+	if len(keys) > 0 {
+		message = strings.Join(keys, ".") + ": " + message
+	}
+	c.errs = append(c.errs, fmt.Errorf("%s:%d: %s", file, node.Line, message))
+}
+
+func (c *checker) check(node *yaml.Node, t reflect.Type, keys []string, file string) {
+	file = c.settings.fileOf(node, file)
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	if node.Tag == "!!null" {
+		return
+	}
+
+	if reflect.PointerTo(t).Implements(unmarshalerType) {
+		if err := node.Decode(reflect.New(t).Interface()); err != nil {
+			c.errorf(node, file, keys, "%v", err)
+		}
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		c.check(node, t.Elem(), keys, file)
+	case reflect.Interface:
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			c.errorf(node, file, keys, "expected a mapping")
+			return
+		}
+		fields := map[string]reflect.Type{}
+		for _, field := range structFields(t) {
+			fields[fieldName(field)] = field.Type
+		}
+		for i := 0; i < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			fieldType, ok := fields[key.Value]
+			if !ok {
+				c.errorf(key, c.settings.fileOf(value, file), keys, "unknown field %q", key.Value)
+				continue
+			}
+			c.check(value, fieldType, append(keys, key.Value), file)
+		}
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			c.errorf(node, file, keys, "expected a mapping")
+			return
+		}
+		for i := 0; i < len(node.Content); i += 2 {
+			c.check(node.Content[i+1], t.Elem(), append(keys, node.Content[i].Value), file)
+		}
+	case reflect.Slice, reflect.Array:
+		if node.Kind != yaml.SequenceNode {
+			c.errorf(node, file, keys, "expected a list")
+			return
+		}
+		for i, item := range node.Content {
+			c.check(item, t.Elem(), append(keys, fmt.Sprint(i)), file)
+		}
+	default:
+		if node.Kind != yaml.ScalarNode {
+			c.errorf(node, file, keys, "expected a %s", t.Kind())
+			return
+		}
+		if err := node.Decode(reflect.New(t).Interface()); err != nil {
+			c.errorf(node, file, keys, "expected a %s, got %q", t.Kind(), node.Value)
+		}
+	}
+}
@@ -0,0 +1,397 @@
+package settings
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// IncludeKey lists glob patterns, relative to the file that declares them, of
+// settings files merged before the rest of that file.
+const IncludeKey = "include"
+
+// AppMetadataKey lists glob patterns, relative to the file that declares
+// them, of application metadata files. These declare the secrets, backup
+// volumes and vendor entries of an application next to its manifests.
+const AppMetadataKey = "app_metadata"
+
+// appSections are the sections application metadata can declare.
+var appSections = []string{"secrets", "backups", "vendors"}
+
+// DropInDir is the directory next to the main settings file whose *.yaml
+// files are merged after it.
+const DropInDir = "settings.d"
+
+// Settings is the merged settings document. Files are merged in this order,
+// with later files taking precedence:
+//
+//  1. the main settings file, e.g. settings.yaml
+//  2. every file in settings.d, sorted by name
+//  3. the application metadata files listed in app_metadata
+//  4. the environment overlay next to the main file, e.g. settings.staging.yaml
+//
+// Every file is preceded by the files it includes. Mappings are merged key
+// by key, any other value replaces the previous one, and a null value
+// removes a key set by an earlier file. Application metadata can only add
+// values, and setting a value to something else is a conflict.
+type Settings struct {
+	root *yaml.Node
+	// sources records the file each merged value came from.
+	sources     map[*yaml.Node]string
+	appMetadata []string
+}
+
+// Load reads the settings file at path and every file merged into it for
+// env, which may be empty to skip the environment overlay.
+func Load(path, env string) (*Settings, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("read file: %w", err)
+	}
+
+	settings := &Settings{
+		root:    &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"},
+		sources: map[*yaml.Node]string{},
+	}
+	settings.sources[settings.root] = path
+
+	files := []string{path}
+	dropIns, err := globFiles(filepath.Join(filepath.Dir(path), DropInDir))
+	if err != nil {
+		return nil, err
+	}
+	files = append(files, dropIns...)
+	for _, file := range files {
+		if err := settings.mergeFile(file, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := settings.mergeAppMetadata(); err != nil {
+		return nil, err
+	}
+
+	if env != "" {
+		overlay := OverlayPath(path, env)
+		if _, err := os.Stat(overlay); err == nil {
+			patterns := len(settings.appMetadata)
+			if err := settings.mergeFile(overlay, nil); err != nil {
+				return nil, err
+			}
+			if len(settings.appMetadata) > patterns {
+				return nil, fmt.Errorf("%s: %s cannot be set in an environment overlay", overlay, AppMetadataKey)
+			}
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("read file: %w", err)
+		}
+	}
+	return settings, nil
+}
+
+// OverlayPath returns the environment overlay of a settings file, which is
+// the file name with the environment inserted before the extension.
+func OverlayPath(path, env string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "." + env + ext
+}
+
+func globFiles(dir string) ([]string, error) {
+	var files []string
+	for _, pattern := range []string{"*.yaml", "*.yml"} {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, matches...)
+	}
+	slices.Sort(files)
+	return files, nil
+}
+
+// mergeFile merges the includes of a file and then the file itself. stack
+// holds the files currently being included, to reject include cycles.
+func (s *Settings) mergeFile(path string, stack []string) error {
+	if slices.Contains(stack, path) {
+		return fmt.Errorf("%s: include cycle: %s", path, strings.Join(append(stack, path), " -> "))
+	}
+	stack = append(stack, path)
+
+	root, err := readFile(path)
+	if err != nil {
+		return err
+	}
+	if root == nil {
+		return nil
+	}
+
+	var includes []string
+	for i := 0; i < len(root.Content); {
+		key, value := root.Content[i], root.Content[i+1]
+		if key.Value != IncludeKey && key.Value != AppMetadataKey {
+			i += 2
+			continue
+		}
+		root.Content = slices.Delete(root.Content, i, i+2)
+
+		patterns, err := filePatterns(path, key.Value, value)
+		if err != nil {
+			return err
+		}
+		if key.Value == IncludeKey {
+			includes = patterns
+		} else {
+			s.appMetadata = append(s.appMetadata, patterns...)
+		}
+	}
+
+	for _, pattern := range includes {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return fmt.Errorf("%s: include %q: %w", path, pattern, err)
+		}
+		if len(matches) == 0 {
+			return fmt.Errorf("%s: include %q matches no files", path, pattern)
+		}
+		for _, match := range matches {
+			if err := s.mergeFile(match, stack); err != nil {
+				return err
+			}
+		}
+	}
+
+	s.merge(s.root, root, path)
+	return nil
+}
+
+// filePatterns returns the glob patterns of an include or app_metadata
+// value, relative to the directory of the file that declares them.
+func filePatterns(path, key string, node *yaml.Node) ([]string, error) {
+	var patterns []string
+	switch node.Kind {
+	case yaml.ScalarNode:
+		patterns = []string{node.Value}
+	case yaml.SequenceNode:
+		if err := node.Decode(&patterns); err != nil {
+			return nil, fmt.Errorf("%s:%d: %s: %w", path, node.Line, key, err)
+		}
+	default:
+		return nil, fmt.Errorf("%s:%d: %s must be a path or a list of paths", path, node.Line, key)
+	}
+
+	for i, pattern := range patterns {
+		if !filepath.IsAbs(pattern) {
+			patterns[i] = filepath.Join(filepath.Dir(path), pattern)
+		}
+		if _, err := filepath.Match(patterns[i], ""); err != nil {
+			return nil, fmt.Errorf("%s:%d: %s %q: %w", path, node.Line, key, pattern, err)
+		}
+	}
+	return patterns, nil
+}
+
+// mergeAppMetadata merges the application metadata files and returns every
+// conflict with the values merged before them.
+func (s *Settings) mergeAppMetadata() error {
+	var files []string
+	for _, pattern := range s.appMetadata {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return fmt.Errorf("%s %q: %w", AppMetadataKey, pattern, err)
+		}
+		files = append(files, matches...)
+	}
+	slices.Sort(files)
+
+	var errs []error
+	for _, file := range slices.Compact(files) {
+		root, err := readFile(file)
+		if err != nil {
+			return err
+		}
+		if root == nil {
+			continue
+		}
+		for i := 0; i < len(root.Content); i += 2 {
+			if key := root.Content[i]; !slices.Contains(appSections, key.Value) {
+				errs = append(errs, fmt.Errorf("%s:%d: %q cannot be declared in application metadata (%s)", file, key.Line, key.Value, strings.Join(appSections, ", ")))
+			}
+		}
+		errs = append(errs, s.mergeStrict(s.root, root, file, s.sources[s.root], nil)...)
+	}
+	return errors.Join(errs...)
+}
+
+// mergeStrict merges the mapping src from file into dst like merge, except
+// that it returns a conflict for every value that dst already sets to
+// something else.
+func (s *Settings) mergeStrict(dst, src *yaml.Node, file, dstFile string, keys []string) []error {
+	var errs []error
+	for i := 0; i < len(src.Content); i += 2 {
+		key, value := src.Content[i], src.Content[i+1]
+		path := append(slices.Clone(keys), key.Value)
+		index := mappingIndex(dst, key.Value)
+
+		if index < 0 {
+			dst.Content = append(dst.Content, key, value)
+			s.sources[value] = file
+			continue
+		}
+
+		existing := dst.Content[index+1]
+		existingFile := s.fileOf(existing, dstFile)
+		switch {
+		case existing.Kind == yaml.MappingNode && value.Kind == yaml.MappingNode:
+			errs = append(errs, s.mergeStrict(existing, value, file, existingFile, path)...)
+		case !sameValue(existing, value):
+			errs = append(errs, fmt.Errorf("%s:%d: %s conflicts with %s:%d", file, key.Line, strings.Join(path, "."), existingFile, dst.Content[index].Line))
+		}
+	}
+	return errs
+}
+
+func sameValue(a, b *yaml.Node) bool {
+	var valueA, valueB any
+	if a.Decode(&valueA) != nil || b.Decode(&valueB) != nil {
+		return false
+	}
+	return reflect.DeepEqual(valueA, valueB)
+}
+
+func readFile(path string) (*yaml.Node, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read file: %w", err)
+	}
+
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("parse YAML %s: %w", path, err)
+	}
+	if len(document.Content) == 0 {
+		return nil, nil
+	}
+	root := document.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("%s: expected a YAML mapping", path)
+	}
+	return root, nil
+}
+
+// merge merges the mapping src from file into dst.
+func (s *Settings) merge(dst, src *yaml.Node, file string) {
+	for i := 0; i < len(src.Content); i += 2 {
+		key, value := src.Content[i], src.Content[i+1]
+		index := mappingIndex(dst, key.Value)
+
+		switch {
+		case index < 0:
+			dst.Content = append(dst.Content, key, value)
+			s.sources[value] = file
+		case value.Tag == "!!null":
+			dst.Content = slices.Delete(dst.Content, index, index+2)
+		case dst.Content[index+1].Kind == yaml.MappingNode && value.Kind == yaml.MappingNode:
+			s.merge(dst.Content[index+1], value, file)
+		default:
+			// The key is replaced too so that its line is in the same file.
+			dst.Content[index], dst.Content[index+1] = key, value
+			s.sources[value] = file
+		}
+	}
+}
+
+func mappingIndex(node *yaml.Node, key string) int {
+	for i := 0; i < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// Decode decodes the merged document into out.
+func (s *Settings) Decode(out any) error {
+	if err := s.root.Decode(out); err != nil {
+		return fmt.Errorf("parse YAML: %w", err)
+	}
+	return nil
+}
+
+// Source returns the file that defines the value at the path of mapping
+// keys. For a mapping merged from several files it is the file that first
+// defined it. It returns "" when the path does not exist.
+func (s *Settings) Source(keys ...string) string {
+	source, _ := s.position(keys)
+	return source
+}
+
+// position returns the file and line of the deepest key of the path that
+// exists.
+func (s *Settings) position(keys []string) (string, int) {
+	node, source, line := s.root, s.sources[s.root], 0
+	for _, key := range keys {
+		if node.Kind != yaml.MappingNode {
+			return "", 0
+		}
+		index := mappingIndex(node, key)
+		if index < 0 {
+			return "", 0
+		}
+		node, line = node.Content[index+1], node.Content[index].Line
+		source = s.fileOf(node, source)
+	}
+	return source, line
+}
+
+// fileOf returns the file a node was merged from, or parent if the node was
+// merged together with its parent.
+func (s *Settings) fileOf(node *yaml.Node, parent string) string {
+	if file, ok := s.sources[node]; ok {
+		return file
+	}
+	return parent
+}
+
+// KeyError is an error about the value at a path of mapping keys, which
+// Locate resolves to the file and line that set the value.
+type KeyError struct {
+	Keys []string
+	Err  error
+}
+
+// AtKeys wraps err in a KeyError, and returns nil when err is nil.
+func AtKeys(err error, keys ...string) error {
+	if err == nil {
+		return nil
+	}
+	return &KeyError{Keys: keys, Err: err}
+}
+
+func (e *KeyError) Error() string { return e.Err.Error() }
+
+func (e *KeyError) Unwrap() error { return e.Err }
+
+// Locate splits joined errors and prefixes each one that wraps a KeyError
+// with the file and line of its key.
+func (s *Settings) Locate(err error) []error {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		var errs []error
+		for _, err := range joined.Unwrap() {
+			errs = append(errs, s.Locate(err)...)
+		}
+		return errs
+	}
+
+	var keyErr *KeyError
+	if errors.As(err, &keyErr) {
+		if file, line := s.position(keyErr.Keys); file != "" && line > 0 {
+			return []error{fmt.Errorf("%s:%d: %w", file, line, err)}
+		}
+	}
+	return []error{err}
+}
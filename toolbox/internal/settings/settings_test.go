@@ -0,0 +1,277 @@
+package settings
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLoadMergesIncludesDropInsAndOverlay(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"settings.yaml": `include: sections/*.yaml
+backups:
+  volumes:
+    finance/actualbudget: {}
+    forgejo/gitea-shared-storage:
+      mover_security_context:
+        runAsUser: 0
+`,
+		"sections/secrets.yaml": `secrets:
+  secret/forgejo/admin:
+    password:
+      type: random
+`,
+		"settings.d/vendors.yaml": `vendors:
+  vendor/charts/dex:
+    kind: chart
+    versions: [0.23.0]
+`,
+		"settings.staging.yaml": `backups:
+  volumes:
+    finance/actualbudget: null
+    forgejo/gitea-shared-storage:
+      mover_security_context:
+        runAsGroup: 0
+vendors:
+  vendor/charts/dex:
+    versions: [0.24.0]
+`,
+	})
+
+	merged, err := Load(filepath.Join(dir, "settings.yaml"), "staging")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var config struct {
+		Secrets map[string]any `yaml:"secrets"`
+		Backups struct {
+			Volumes map[string]struct {
+				MoverSecurityContext map[string]int `yaml:"mover_security_context"`
+			} `yaml:"volumes"`
+		} `yaml:"backups"`
+		Vendors map[string]struct {
+			Kind     string   `yaml:"kind"`
+			Versions []string `yaml:"versions"`
+		} `yaml:"vendors"`
+	}
+	if err := merged.Decode(&config); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := config.Secrets["secret/forgejo/admin"]; !ok {
+		t.Errorf("expected included secrets, got %v", config.Secrets)
+	}
+	if _, ok := config.Backups.Volumes["finance/actualbudget"]; ok {
+		t.Errorf("expected the overlay to remove finance/actualbudget, got %v", config.Backups.Volumes)
+	}
+	if got := config.Backups.Volumes["forgejo/gitea-shared-storage"].MoverSecurityContext; len(got) != 2 {
+		t.Errorf("expected merged mover_security_context, got %v", got)
+	}
+	dex := config.Vendors["vendor/charts/dex"]
+	if dex.Kind != "chart" || strings.Join(dex.Versions, ",") != "0.24.0" {
+		t.Errorf("expected overlay versions with the base kind, got %+v", dex)
+	}
+
+	sources := map[string][]string{
+		"settings.yaml":           {"backups"},
+		"sections/secrets.yaml":   {"secrets", "secret/forgejo/admin"},
+		"settings.d/vendors.yaml": {"vendors", "vendor/charts/dex", "kind"},
+		"settings.staging.yaml":   {"vendors", "vendor/charts/dex", "versions"},
+	}
+	for want, keys := range sources {
+		if got := merged.Source(keys...); got != filepath.Join(dir, want) {
+			t.Errorf("Source(%v) = %q, want %q", keys, got, filepath.Join(dir, want))
+		}
+	}
+	if got := merged.Source("registries"); got != "" {
+		t.Errorf("expected no source for a missing key, got %q", got)
+	}
+}
+
+func TestLoadWithoutOverlay(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"settings.yaml":            "vendors: {}\n",
+		"settings.production.yaml": "vendors: null\n",
+	})
+
+	merged, err := Load(filepath.Join(dir, "settings.yaml"), "staging")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := merged.Source("vendors"); got != filepath.Join(dir, "settings.yaml") {
+		t.Fatalf("expected vendors from the main file, got %q", got)
+	}
+}
+
+func TestLoadRejectsInvalidIncludes(t *testing.T) {
+	cases := []struct {
+		name    string
+		files   map[string]string
+		wantErr string
+	}{
+		{"cycle", map[string]string{
+			"settings.yaml": "include: a.yaml\n",
+			"a.yaml":        "include: settings.yaml\n",
+		}, "include cycle"},
+		{"missing", map[string]string{
+			"settings.yaml": "include: [secrets.yaml]\n",
+		}, "matches no files"},
+		{"not a mapping", map[string]string{
+			"settings.yaml": "include: a.yaml\n",
+			"a.yaml":        "- secrets\n",
+		}, "expected a YAML mapping"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := writeFiles(t, tc.files)
+			_, err := Load(filepath.Join(dir, "settings.yaml"), "")
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("expected error %q, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestCheckAndLocateReportPositions(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"settings.yaml": `vendors:
+  dex:
+    kind: chart
+    versions: 0.23.0
+    chrat: dex
+`,
+		"settings.staging.yaml": `vendors:
+  vault:
+    versions: [true]
+`,
+	})
+	merged, err := Load(filepath.Join(dir, "settings.yaml"), "staging")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type vendor struct {
+		Kind     string   `yaml:"kind"`
+		Versions []string `yaml:"versions"`
+	}
+	var config struct {
+		Vendors map[string]vendor `yaml:"vendors"`
+	}
+	var got []string
+	for _, err := range merged.Check(&config) {
+		got = append(got, strings.TrimPrefix(err.Error(), dir+string(filepath.Separator)))
+	}
+	want := []string{
+		`settings.yaml:4: vendors.dex.versions: expected a list`,
+		`settings.yaml:5: vendors.dex: unknown field "chrat"`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected check errors:\n%s", strings.Join(got, "\n"))
+	}
+
+	located := merged.Locate(errors.Join(
+		AtKeys(errors.New("vendors.vault: kind is required"), "vendors", "vault"),
+		errors.New("unrelated"),
+	))
+	if len(located) != 2 || located[0].Error() != filepath.Join(dir, "settings.staging.yaml")+":2: vendors.vault: kind is required" || located[1].Error() != "unrelated" {
+		t.Fatalf("unexpected located errors: %v", located)
+	}
+}
+
+func TestSchema(t *testing.T) {
+	var config struct {
+		Secrets map[string]struct {
+			Type string `yaml:"type" enum:"random,manual"`
+		} `yaml:"secrets"`
+	}
+	schema := Schema(&config)
+	secrets := schema["properties"].(map[string]any)["secrets"].(map[string]any)
+	secret := secrets["additionalProperties"].(map[string]any)
+	if secret["additionalProperties"] != false {
+		t.Errorf("expected unknown fields to be rejected, got %v", secret)
+	}
+	kind := secret["properties"].(map[string]any)["type"].(map[string]any)
+	if strings.Join(kind["enum"].([]string), ",") != "random,manual" {
+		t.Errorf("expected enum from the struct tag, got %v", kind)
+	}
+	if _, ok := schema["properties"].(map[string]any)[IncludeKey]; !ok {
+		t.Errorf("expected %s in the schema", IncludeKey)
+	}
+}
+
+func TestLoadMergesAppMetadata(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"settings.yaml": `app_metadata: apps/*/metadata.json
+secrets:
+  secret/forgejo/admin:
+    password:
+      type: random
+`,
+		"apps/finance/metadata.json": `{
+  "secrets": {
+    "secret/forgejo/admin": {"password": {"type": "random"}},
+    "secret/actualbudget/auth": {"client_secret": {"type": "random"}}
+  },
+  "backups": {"volumes": {"finance-actualbudget-production/actualbudget": {}}}
+}`,
+		"apps/khuedoan/metadata.json": `{}`,
+	})
+
+	merged, err := Load(filepath.Join(dir, "settings.yaml"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := merged.Source("secrets", "secret/actualbudget/auth"); got != filepath.Join(dir, "apps/finance/metadata.json") {
+		t.Errorf("expected the secret from the app metadata, got %q", got)
+	}
+	if got := merged.Source("secrets", "secret/forgejo/admin", "password"); got != filepath.Join(dir, "settings.yaml") {
+		t.Errorf("expected the identical secret to stay in settings.yaml, got %q", got)
+	}
+}
+
+func TestLoadReportsAppMetadataConflicts(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"settings.yaml": `app_metadata: [apps/*/metadata.json]
+secrets:
+  secret/test/example:
+    MOCK_API_KEY:
+      type: random
+`,
+		"apps/test/metadata.json": `{
+  "secrets": {"secret/test/example": {"MOCK_API_KEY": {"type": "manual"}}},
+  "registries": {}
+}`,
+	})
+
+	_, err := Load(filepath.Join(dir, "settings.yaml"), "")
+	if err == nil {
+		t.Fatal("expected conflicts, got nil")
+	}
+	for _, want := range []string{
+		`apps/test/metadata.json:3: "registries" cannot be declared in application metadata`,
+		`apps/test/metadata.json:2: secrets.secret/test/example.MOCK_API_KEY.type conflicts with ` + filepath.Join(dir, "settings.yaml") + ":5",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in %v", want, err)
+		}
+	}
+}
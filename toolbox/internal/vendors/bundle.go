@@ -0,0 +1,433 @@
+package vendors
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	k8syaml "sigs.k8s.io/yaml"
+)
+
+const (
+	helmConfigMediaType     = "application/vnd.cncf.helm.config.v1+json"
+	helmChartMediaType      = "application/vnd.cncf.helm.chart.content.v1.tar+gzip"
+	helmProvenanceMediaType = "application/vnd.cncf.helm.chart.provenance.v1.prov"
+	refNameAnnotation       = "org.opencontainers.image.ref.name"
+	// gitBundleDir holds a git bundle per git entry, next to the OCI layouts.
+	gitBundleDir = "git"
+)
+
+// Export writes every chart and image into an OCI image layout per registry
+// repository, and every git entry into a git bundle, and packs them into a
+// tarball at output. The directory of each layout inside the tarball is the
+// repository it is imported into.
+func Export(ctx context.Context, workdir, output string, entries []VendorEntry) error {
+	layouts := filepath.Join(workdir, "bundle")
+	for _, item := range entries {
+		layout := filepath.Join(layouts, filepath.FromSlash(RegistryRepository(item)))
+		var err error
+		switch item.Kind {
+		case "chart":
+			err = exportChart(ctx, workdir, layout, item)
+		case "image":
+			err = exportImage(ctx, layout, item)
+		case "artifact":
+			err = exportArtifact(ctx, layout, item)
+		case "file":
+			err = exportFile(ctx, workdir, layout, item)
+		case "git":
+			err = exportGit(ctx, workdir, filepath.Join(layouts, gitBundleDir), item)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := writeTar(layouts, output); err != nil {
+		return fmt.Errorf("write bundle: %w", err)
+	}
+	return nil
+}
+
+// Import pushes every OCI layout in the bundle into every destination, and
+// every git bundle into the git remote, which is only needed when the bundle
+// contains git entries.
+func Import(ctx context.Context, workdir, input string, destinations []Destination, remote *GitRemote) error {
+	layouts := filepath.Join(workdir, "bundle")
+	if err := extractTar(input, layouts); err != nil {
+		return fmt.Errorf("read bundle: %w", err)
+	}
+
+	err := filepath.WalkDir(layouts, func(layout string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || entry.Name() != "oci-layout" {
+			return err
+		}
+		layout = filepath.Dir(layout)
+		relative, err := filepath.Rel(layouts, layout)
+		if err != nil {
+			return err
+		}
+		repository := filepath.ToSlash(relative)
+
+		refs, err := layoutRefs(layout)
+		if err != nil {
+			return fmt.Errorf("read layout %s: %w", repository, err)
+		}
+		for _, ref := range refs {
+			source := layout + ref
+			for _, registry := range destinations {
+				log.Infof("importing %s%s to %s", repository, ref, registry.Name)
+				destination := registry.Address + "/" + repository + ref
+				args := append([]string{"cp", "--recursive", "--from-oci-layout", source, destination}, registry.orasTargetArgs()...)
+				err := withRetry(ctx, "import "+repository+ref, func() error {
+					return runCommand(ctx, "oras", args...)
+				})
+				if err != nil {
+					return fmt.Errorf("import %s%s to %s: %w", repository, ref, registry.Name, err)
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return importGitBundles(ctx, workdir, filepath.Join(layouts, gitBundleDir), remote)
+}
+
+// BundleHasGit reports whether a bundle written by Export contains git
+// entries, which need a git remote to import.
+func BundleHasGit(input string) (bool, error) {
+	file, err := os.Open(input)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	reader := tar.NewReader(file)
+	for {
+		header, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return false, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("read bundle: %w", err)
+		}
+		if strings.HasPrefix(header.Name, gitBundleDir+"/") && strings.HasSuffix(header.Name, ".bundle") {
+			return true, nil
+		}
+	}
+}
+
+// exportGit writes the versions of a git entry into a git bundle named after
+// the entry.
+func exportGit(ctx context.Context, workdir, dir string, repository VendorEntry) error {
+	gitDir, err := initGitDir(ctx, workdir, repository.Name)
+	if err != nil {
+		return err
+	}
+	refs := make([]string, 0, len(repository.Versions))
+	for _, version := range repository.Versions {
+		log.Infof("exporting git %s@%s", repository.Name, version)
+		ref, _, err := fetchGitVersion(ctx, gitDir, repository, version)
+		if err != nil {
+			return err
+		}
+		refs = append(refs, ref)
+	}
+
+	bundle := filepath.Join(dir, filepath.FromSlash(repository.Name)+".bundle")
+	if err := os.MkdirAll(filepath.Dir(bundle), 0o755); err != nil {
+		return fmt.Errorf("create git bundle dir: %w", err)
+	}
+	if _, err := commandOutput(ctx, "git", append([]string{"--git-dir", gitDir, "bundle", "create", "--quiet", bundle}, refs...)...); err != nil {
+		return fmt.Errorf("export git %s: %w", repository.Name, err)
+	}
+	return nil
+}
+
+// importGitBundles pushes every git bundle in dir into the Forgejo
+// repository it was exported from.
+func importGitBundles(ctx context.Context, workdir, dir string, remote *GitRemote) error {
+	return filepath.WalkDir(dir, func(bundle string, entry fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) && bundle == dir {
+			return nil
+		}
+		if err != nil || entry.IsDir() || filepath.Ext(bundle) != ".bundle" {
+			return err
+		}
+		relative, err := filepath.Rel(dir, bundle)
+		if err != nil {
+			return err
+		}
+		name := strings.TrimSuffix(filepath.ToSlash(relative), ".bundle")
+		if remote == nil {
+			return fmt.Errorf("import git %s: no git remote configured", name)
+		}
+
+		log.Infof("importing git %s", name)
+		if err := remote.ensureRepository(ctx, name); err != nil {
+			return fmt.Errorf("create Forgejo repository %s: %w", name, err)
+		}
+		gitDir, err := initGitDir(ctx, filepath.Join(workdir, "git"), name)
+		if err != nil {
+			return err
+		}
+		if _, err := commandOutput(ctx, "git", "--git-dir", gitDir, "fetch", "--quiet", bundle, "+refs/*:refs/*"); err != nil {
+			return fmt.Errorf("read git bundle %s: %w", name, err)
+		}
+		if err := remote.push(ctx, gitDir, name, "+refs/*:refs/*"); err != nil {
+			return fmt.Errorf("import git %s: %w", name, err)
+		}
+		return nil
+	})
+}
+
+func exportImage(ctx context.Context, layout string, image VendorEntry) error {
+	for _, version := range image.Versions {
+		log.Infof("exporting image %s:%s", image.Name, version)
+
+		source, target := imageRefs(image, version)
+		target = layout + strings.TrimPrefix(target, image.Name)
+		copyArgs := []string{"cp", source, target, "--to-oci-layout"}
+
+		var digest string
+		if image.Verify != nil {
+			var err error
+			if digest, err = verifyImage(ctx, image, source); err != nil {
+				return fmt.Errorf("verify image %s@%s: %w", image.Name, version, err)
+			}
+			copyArgs = []string{"cp", "--recursive", image.Source + "@" + digest, target, "--to-oci-layout"}
+		}
+
+		copyArgs = append(copyArgs, image.Auth.orasSourceArgs()...)
+		if err := runCommand(ctx, "oras", copyArgs...); err != nil {
+			return fmt.Errorf("export image %s@%s: %w", image.Name, version, err)
+		}
+
+		if digest != "" {
+			if err := copySignatureTags(ctx, image, digest, layout, "--to-oci-layout"); err != nil {
+				return fmt.Errorf("export signatures for %s@%s: %w", image.Name, version, err)
+			}
+		}
+	}
+	return nil
+}
+
+func exportArtifact(ctx context.Context, layout string, artifact VendorEntry) error {
+	for _, version := range artifact.Versions {
+		log.Infof("exporting artifact %s:%s", artifact.Name, version)
+
+		source, target := imageRefs(artifact, version)
+		target = layout + strings.TrimPrefix(target, artifact.Name)
+		copyArgs := append([]string{"cp", "--recursive", source, target, "--to-oci-layout"}, artifact.Auth.orasSourceArgs()...)
+		if err := runCommand(ctx, "oras", copyArgs...); err != nil {
+			return fmt.Errorf("export artifact %s@%s: %w", artifact.Name, version, err)
+		}
+	}
+	return nil
+}
+
+func exportFile(ctx context.Context, workdir, layout string, file VendorEntry) error {
+	for _, version := range file.Versions {
+		log.Infof("exporting file %s@%s", file.Name, version)
+
+		filePath, err := downloadFile(ctx, workdir, file, version, nil)
+		if err != nil {
+			return err
+		}
+		if err := pushFile(ctx, filePath, layout+":"+RegistryTag(version), "--oci-layout"); err != nil {
+			return fmt.Errorf("export file %s@%s: %w", file.Name, version, err)
+		}
+	}
+	return nil
+}
+
+func exportChart(ctx context.Context, workdir, layout string, chart VendorEntry) error {
+	for _, version := range chart.Versions {
+		log.Infof("exporting chart %s@%s", chart.Name, version)
+
+		archivePath, err := pullChart(ctx, workdir, chart, version)
+		if err != nil {
+			return err
+		}
+		if err := pushChartToLayout(ctx, archivePath, layout+":"+RegistryTag(version)); err != nil {
+			return fmt.Errorf("export chart %s@%s: %w", chart.Name, version, err)
+		}
+	}
+	return nil
+}
+
+// pushChartToLayout stores a chart archive with the same manifest layout that
+// helm push uses, including the provenance file when one was pulled.
+func pushChartToLayout(ctx context.Context, archivePath, target string) error {
+	metadata, err := chartMetadata(archivePath)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(archivePath)
+	configPath := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(configPath, metadata, 0o644); err != nil {
+		return fmt.Errorf("write chart config: %w", err)
+	}
+
+	archive := filepath.Base(archivePath)
+	args := []string{"push", "--oci-layout", target, "--config", "config.json:" + helmConfigMediaType, archive + ":" + helmChartMediaType}
+	if _, err := os.Stat(archivePath + ".prov"); err == nil {
+		args = append(args, archive+".prov:"+helmProvenanceMediaType)
+	}
+
+	// oras push rejects absolute file paths, so run it next to the files.
+	return runCommandIn(ctx, dir, "oras", args...)
+}
+
+// chartMetadata returns Chart.yaml from a chart archive as JSON, which is what
+// Helm stores as the OCI config blob.
+func chartMetadata(archivePath string) ([]byte, error) {
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return nil, fmt.Errorf("open chart: %w", err)
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("read chart: %w", err)
+	}
+	reader := tar.NewReader(gz)
+	for {
+		header, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("Chart.yaml not found in %s", archivePath)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read chart: %w", err)
+		}
+		if dir, name := path.Split(header.Name); name != "Chart.yaml" || strings.Count(dir, "/") != 1 {
+			continue
+		}
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			return nil, fmt.Errorf("read Chart.yaml: %w", err)
+		}
+		metadata, err := k8syaml.YAMLToJSON(data)
+		if err != nil {
+			return nil, fmt.Errorf("convert Chart.yaml: %w", err)
+		}
+		return metadata, nil
+	}
+}
+
+// layoutRefs returns the tags in an OCI layout as ":<tag>", and untagged
+// manifests as "@<digest>".
+func layoutRefs(layout string) ([]string, error) {
+	data, err := os.ReadFile(filepath.Join(layout, "index.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var index struct {
+		Manifests []struct {
+			Digest      string            `json:"digest"`
+			Annotations map[string]string `json:"annotations"`
+		} `json:"manifests"`
+	}
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("parse index.json: %w", err)
+	}
+
+	refs := make([]string, 0, len(index.Manifests))
+	for _, manifest := range index.Manifests {
+		if tag := manifest.Annotations[refNameAnnotation]; tag != "" {
+			refs = append(refs, ":"+tag)
+		} else {
+			refs = append(refs, "@"+manifest.Digest)
+		}
+	}
+	return refs, nil
+}
+
+func writeTar(dir, output string) error {
+	file, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := tar.NewWriter(file)
+	if err := writer.AddFS(os.DirFS(dir)); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+func extractTar(input, dir string) error {
+	file, err := os.Open(input)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	root, err := os.OpenRoot(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+		root, err = os.OpenRoot(dir)
+	}
+	if err != nil {
+		return err
+	}
+	defer root.Close()
+
+	reader := tar.NewReader(file)
+	for {
+		header, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		// os.Root rejects paths that escape the bundle directory.
+		name := filepath.FromSlash(header.Name)
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := root.MkdirAll(name, 0o755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := root.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+				return err
+			}
+			target, err := root.Create(name)
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(target, reader); err != nil {
+				target.Close()
+				return err
+			}
+			if err := target.Close(); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unsupported entry %s in bundle", header.Name)
+		}
+	}
+}
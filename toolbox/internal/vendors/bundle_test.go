@@ -0,0 +1,144 @@
+package vendors
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestBundleRoundTrip(t *testing.T) {
+	source := filepath.Join(t.TempDir(), "bundle")
+	layout := filepath.Join(source, "vendor", "images", "dexidp", "dex")
+	if err := os.MkdirAll(layout, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"oci-layout": `{"imageLayoutVersion":"1.0.0"}`,
+		"index.json": `{"manifests":[
+			{"digest":"sha256:aaa","annotations":{"org.opencontainers.image.ref.name":"v2.43.1"}},
+			{"digest":"sha256:bbb"}
+		]}`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(layout, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	output := filepath.Join(t.TempDir(), "bundle.tar")
+	if err := writeTar(source, output); err != nil {
+		t.Fatalf("write bundle: %v", err)
+	}
+	extracted := filepath.Join(t.TempDir(), "bundle")
+	if err := extractTar(output, extracted); err != nil {
+		t.Fatalf("extract bundle: %v", err)
+	}
+
+	refs, err := layoutRefs(filepath.Join(extracted, "vendor", "images", "dexidp", "dex"))
+	if err != nil {
+		t.Fatalf("read layout: %v", err)
+	}
+	if want := []string{":v2.43.1", "@sha256:bbb"}; !slices.Equal(refs, want) {
+		t.Fatalf("expected refs %v, got %v", want, refs)
+	}
+}
+
+func TestExtractTarRejectsEscapingPaths(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "bundle.tar")
+	file, err := os.Create(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writer := tar.NewWriter(file)
+	if err := writer.WriteHeader(&tar.Header{Name: "../escape", Mode: 0o644, Size: 1, Typeflag: tar.TypeReg}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := writer.Write([]byte("x")); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	if err := extractTar(output, filepath.Join(t.TempDir(), "bundle")); err == nil {
+		t.Fatal("expected error for path outside the bundle, got nil")
+	}
+}
+
+func TestChartMetadata(t *testing.T) {
+	archivePath := filepath.Join(t.TempDir(), "dex-0.23.0.tgz")
+	file, err := os.Create(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gz := gzip.NewWriter(file)
+	writer := tar.NewWriter(gz)
+	for name, content := range map[string]string{
+		"dex/Chart.yaml":               "apiVersion: v2\nname: dex\nversion: 0.23.0\n",
+		"dex/charts/common/Chart.yaml": "apiVersion: v2\nname: common\nversion: 1.0.0\n",
+	} {
+		if err := writer.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := writer.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writer.Close()
+	gz.Close()
+	file.Close()
+
+	metadata, err := chartMetadata(archivePath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := `{"apiVersion":"v2","name":"dex","version":"0.23.0"}`; string(metadata) != want {
+		t.Fatalf("expected %s, got %s", want, metadata)
+	}
+}
+
+func TestGitBundleRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	source := filepath.Join(t.TempDir(), "dex")
+	for _, args := range [][]string{
+		{"init", "--quiet", source},
+		{"-C", source, "-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--quiet", "--allow-empty", "-m", "initial"},
+		{"-C", source, "tag", "v2.43.1"},
+	} {
+		if _, err := commandOutput(ctx, "git", args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	output := filepath.Join(t.TempDir(), "bundle.tar")
+	entries := []VendorEntry{{Name: "vendor/dex", Vendor: Vendor{Kind: "git", RepoURL: source, Versions: []string{"v2.43.1"}}}}
+	if err := Export(ctx, t.TempDir(), output, entries); err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	if hasGit, err := BundleHasGit(output); err != nil || !hasGit {
+		t.Fatalf("expected bundle to contain git entries, got %v, %v", hasGit, err)
+	}
+
+	extracted := filepath.Join(t.TempDir(), "bundle")
+	if err := extractTar(output, extracted); err != nil {
+		t.Fatalf("extract bundle: %v", err)
+	}
+	heads, err := commandOutput(ctx, "git", "bundle", "list-heads", filepath.Join(extracted, gitBundleDir, "vendor", "dex.bundle"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(heads), "refs/tags/v2.43.1") {
+		t.Errorf("expected git bundle to contain v2.43.1, got %q", heads)
+	}
+
+	err = Import(ctx, t.TempDir(), output, nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "no git remote configured") {
+		t.Errorf("expected import without a git remote to fail, got %v", err)
+	}
+}
@@ -1,102 +1,171 @@
 package vendors
 
 import (
+	"errors"
 	"fmt"
-	"os"
+	"maps"
+	"regexp"
 	"slices"
 	"strings"
 
-	"gopkg.in/yaml.v3"
+	"github.com/khuedoan/cloudlab/toolbox/internal/settings"
 )
 
+const versionPlaceholder = "{version}"
+
+var sha256Pattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
 type Config struct {
-	Items map[string]Vendor `yaml:"vendors"`
+	Items       map[string]Vendor           `yaml:"vendors"`
+	Credentials map[string]CredentialRef    `yaml:"vendor_credentials,omitempty"`
+	Registries  map[string]RegistrySettings `yaml:"registries,omitempty"`
 }
 
 type Vendor struct {
-	Kind     string   `yaml:"kind"`
-	RepoURL  string   `yaml:"repo_url,omitempty"`
-	Ref      string   `yaml:"ref,omitempty"`
-	Chart    string   `yaml:"chart,omitempty"`
-	Versions []string `yaml:"versions"`
-	Source   string   `yaml:"source,omitempty"`
+	Kind        string            `yaml:"kind" enum:"chart,image,artifact,file,git"`
+	RepoURL     string            `yaml:"repo_url,omitempty"`
+	Ref         string            `yaml:"ref,omitempty"`
+	Chart       string            `yaml:"chart,omitempty"`
+	Versions    []string          `yaml:"versions"`
+	Source      string            `yaml:"source,omitempty"`
+	URL         string            `yaml:"url,omitempty"`
+	SHA256      map[string]string `yaml:"sha256,omitempty"`
+	Verify      *Verification     `yaml:"verify,omitempty"`
+	Credentials *CredentialRef    `yaml:"credentials,omitempty"`
+}
+
+// Verification declares how upstream artifacts are verified before they are pushed.
+// Images use cosign with either a public key or a keyless identity, charts use
+// Helm provenance files checked against a keyring.
+type Verification struct {
+	Key            string `yaml:"key,omitempty"`
+	Identity       string `yaml:"identity,omitempty"`
+	IdentityRegexp string `yaml:"identity_regexp,omitempty"`
+	Issuer         string `yaml:"issuer,omitempty"`
+	IssuerRegexp   string `yaml:"issuer_regexp,omitempty"`
+	Keyring        string `yaml:"keyring,omitempty"`
 }
 
 type VendorEntry struct {
 	Name string
 	Vendor
+	Auth *Auth
 }
 
-func LoadConfig(configPath string) (*Config, error) {
-	data, err := os.ReadFile(configPath)
+// LoadConfig loads the vendor settings from the settings file at configPath
+// merged with its includes and the overlay for env.
+func LoadConfig(configPath, env string) (*Config, error) {
+	merged, err := settings.Load(configPath, env)
 	if err != nil {
-		return nil, fmt.Errorf("read file: %w", err)
+		return nil, err
 	}
 
 	var config Config
-	if err := yaml.Unmarshal(data, &config); err != nil {
-		return nil, fmt.Errorf("parse YAML: %w", err)
+	if err := merged.Decode(&config); err != nil {
+		return nil, err
 	}
 
 	return &config, nil
 }
 
 func ParseAndValidate(config *Config) ([]VendorEntry, error) {
-	names := make([]string, 0, len(config.Items))
-	for name := range config.Items {
-		names = append(names, name)
+	var errs []error
+	if err := validateRegistries(config.Registries); err != nil {
+		errs = append(errs, err)
+	}
+	for _, host := range slices.Sorted(maps.Keys(config.Credentials)) {
+		if err := validateCredentialRef("vendor_credentials."+host, config.Credentials[host]); err != nil {
+			errs = append(errs, settings.AtKeys(err, "vendor_credentials", host))
+		}
 	}
-	slices.Sort(names)
 
-	entries := make([]VendorEntry, 0, len(names))
+	entries := make([]VendorEntry, 0, len(config.Items))
+	for _, name := range slices.Sorted(maps.Keys(config.Items)) {
+		vendor, err := validateVendor(name, config.Items[name], config.Credentials)
+		if err != nil {
+			errs = append(errs, settings.AtKeys(err, "vendors", name))
+			continue
+		}
+		entries = append(entries, VendorEntry{Name: name, Vendor: vendor})
+	}
 
-	for _, name := range names {
-		vendor := config.Items[name]
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
 
-		if err := validateDestination(name); err != nil {
-			return nil, err
-		}
+// validateVendor returns the entry with its kind normalized and the
+// credentials of its source host applied.
+func validateVendor(name string, vendor Vendor, hostCredentials map[string]CredentialRef) (Vendor, error) {
+	if err := validateDestination(name); err != nil {
+		return vendor, err
+	}
 
-		vendor.Kind = strings.ToLower(vendor.Kind)
+	vendor.Kind = strings.ToLower(vendor.Kind)
 
-		if len(vendor.Versions) == 0 {
-			return nil, fmt.Errorf("vendors.%s: versions is required", name)
+	if len(vendor.Versions) == 0 {
+		return vendor, fmt.Errorf("vendors.%s: versions is required", name)
+	}
+
+	for _, version := range vendor.Versions {
+		if version == "" {
+			return vendor, fmt.Errorf("vendors.%s: versions cannot be empty", name)
 		}
+	}
 
-		for _, version := range vendor.Versions {
-			if version == "" {
-				return nil, fmt.Errorf("vendors.%s: versions cannot be empty", name)
+	switch vendor.Kind {
+	case "chart":
+		if vendor.Ref != "" {
+			if vendor.RepoURL != "" || vendor.Chart != "" {
+				return vendor, fmt.Errorf("vendors.%s: use either ref or repo_url/chart", name)
+			}
+		} else {
+			if vendor.RepoURL == "" || vendor.Chart == "" {
+				return vendor, fmt.Errorf("vendors.%s: repo_url and chart are both required", name)
 			}
 		}
 
-		switch vendor.Kind {
-		case "chart":
-			if vendor.Ref != "" {
-				if vendor.RepoURL != "" || vendor.Chart != "" {
-					return nil, fmt.Errorf("vendors.%s: use either ref or repo_url/chart", name)
-				}
-			} else {
-				if vendor.RepoURL == "" || vendor.Chart == "" {
-					return nil, fmt.Errorf("vendors.%s: repo_url and chart are both required", name)
-				}
-			}
+	case "image", "artifact":
+		if vendor.Source == "" {
+			return vendor, fmt.Errorf("vendors.%s: source is required", name)
+		}
 
-		case "image":
-			if vendor.Source == "" {
-				return nil, fmt.Errorf("vendors.%s: source is required", name)
-			}
+	case "file":
+		if err := validateFile(name, vendor); err != nil {
+			return vendor, err
+		}
 
-		default:
-			if vendor.Kind == "" {
-				return nil, fmt.Errorf("vendors.%s: kind is required (chart|image)", name)
-			}
-			return nil, fmt.Errorf("vendors.%s: invalid kind %q", name, vendor.Kind)
+	case "git":
+		if vendor.RepoURL == "" {
+			return vendor, fmt.Errorf("vendors.%s: repo_url is required", name)
+		}
+		if owner, repository, ok := strings.Cut(name, "/"); !ok || owner == "" || repository == "" || strings.Contains(repository, "/") {
+			return vendor, fmt.Errorf("vendors.%s: git destination must be a Forgejo owner/repository", name)
 		}
 
-		entries = append(entries, VendorEntry{Name: name, Vendor: vendor})
+	default:
+		if vendor.Kind == "" {
+			return vendor, fmt.Errorf("vendors.%s: kind is required (%s)", name, strings.Join(kinds, "|"))
+		}
+		return vendor, fmt.Errorf("vendors.%s: invalid kind %q", name, vendor.Kind)
 	}
 
-	return entries, nil
+	if err := validateVerify(name, vendor); err != nil {
+		return vendor, err
+	}
+
+	if vendor.Credentials == nil {
+		if ref, ok := hostCredentials[vendor.SourceHost()]; ok {
+			vendor.Credentials = &ref
+		}
+	}
+	if vendor.Credentials != nil {
+		if err := validateCredentialRef("vendors."+name+".credentials", *vendor.Credentials); err != nil {
+			return vendor, err
+		}
+	}
+	return vendor, nil
 }
 
 func validateDestination(destination string) error {
@@ -111,3 +180,59 @@ func validateDestination(destination string) error {
 	}
 	return nil
 }
+
+func validateFile(name string, vendor Vendor) error {
+	if !strings.HasPrefix(vendor.URL, "https://") {
+		return fmt.Errorf("vendors.%s: url must be an https:// URL", name)
+	}
+	if len(vendor.Versions) > 1 && !strings.Contains(vendor.URL, versionPlaceholder) {
+		return fmt.Errorf("vendors.%s: url must contain %s when listing multiple versions", name, versionPlaceholder)
+	}
+	for _, version := range vendor.Versions {
+		if !sha256Pattern.MatchString(vendor.SHA256[version]) {
+			return fmt.Errorf("vendors.%s: sha256.%s must be a hex-encoded SHA-256 digest", name, version)
+		}
+	}
+	return nil
+}
+
+func validateVerify(name string, vendor Vendor) error {
+	verify := vendor.Verify
+	if verify == nil {
+		return nil
+	}
+	if vendor.Kind != "chart" && vendor.Kind != "image" {
+		return fmt.Errorf("vendors.%s: verify is not supported for kind %s", name, vendor.Kind)
+	}
+
+	keyless := verify.Identity != "" || verify.IdentityRegexp != "" || verify.Issuer != "" || verify.IssuerRegexp != ""
+	switch vendor.Kind {
+	case "chart":
+		if verify.Keyring == "" {
+			return fmt.Errorf("vendors.%s: verify.keyring is required for charts", name)
+		}
+		if verify.Key != "" || keyless {
+			return fmt.Errorf("vendors.%s: charts only support verify.keyring", name)
+		}
+
+	case "image":
+		if verify.Keyring != "" {
+			return fmt.Errorf("vendors.%s: verify.keyring is only supported for charts", name)
+		}
+		if verify.Identity != "" && verify.IdentityRegexp != "" {
+			return fmt.Errorf("vendors.%s: use either verify.identity or verify.identity_regexp", name)
+		}
+		if verify.Issuer != "" && verify.IssuerRegexp != "" {
+			return fmt.Errorf("vendors.%s: use either verify.issuer or verify.issuer_regexp", name)
+		}
+		switch {
+		case verify.Key != "" && keyless:
+			return fmt.Errorf("vendors.%s: use either verify.key or a keyless identity", name)
+		case verify.Key == "" && !keyless:
+			return fmt.Errorf("vendors.%s: verify requires key or identity and issuer", name)
+		case keyless && (verify.Identity == "" && verify.IdentityRegexp == "" || verify.Issuer == "" && verify.IssuerRegexp == ""):
+			return fmt.Errorf("vendors.%s: keyless verification requires both an identity and an issuer", name)
+		}
+	}
+	return nil
+}
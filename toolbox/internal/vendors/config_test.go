@@ -23,6 +23,54 @@ func TestParseAndValidate(t *testing.T) {
 		{"valid image versions", &Config{Items: map[string]Vendor{
 			"vendor/images/dex": {Kind: "image", Source: "ghcr.io/dexidp/dex", Versions: []string{"v2.43.1"}},
 		}}, ""},
+		{"keyless image verification without issuer", &Config{Items: map[string]Vendor{
+			"vendor/images/dex": {Kind: "image", Source: "ghcr.io/dexidp/dex", Versions: []string{"v2.43.1"}, Verify: &Verification{
+				IdentityRegexp: "^https://github.com/dexidp/dex/",
+			}},
+		}}, "requires both an identity and an issuer"},
+		{"image verification with key and identity", &Config{Items: map[string]Vendor{
+			"vendor/images/dex": {Kind: "image", Source: "ghcr.io/dexidp/dex", Versions: []string{"v2.43.1"}, Verify: &Verification{
+				Key: "cosign.pub", Identity: "ci@example.com", Issuer: "https://token.actions.githubusercontent.com",
+			}},
+		}}, "use either verify.key or a keyless identity"},
+		{"chart verification without keyring", &Config{Items: map[string]Vendor{
+			"vendor/charts/dex": {Kind: "chart", Chart: "dex", RepoURL: "https://charts.dexidp.io", Versions: []string{"0.23.0"}, Verify: &Verification{
+				Key: "cosign.pub",
+			}},
+		}}, "verify.keyring is required"},
+		{"valid keyless image verification", &Config{Items: map[string]Vendor{
+			"vendor/images/dex": {Kind: "image", Source: "ghcr.io/dexidp/dex", Versions: []string{"v2.43.1"}, Verify: &Verification{
+				IdentityRegexp: "^https://github.com/dexidp/dex/", Issuer: "https://token.actions.githubusercontent.com",
+			}},
+		}}, ""},
+		{"file without checksum", &Config{Items: map[string]Vendor{
+			"vendor/files/talos": {Kind: "file", URL: "https://factory.talos.dev/image/metal-amd64.raw.xz", Versions: []string{"v1.11.2"}},
+		}}, "sha256.v1.11.2 must be"},
+		{"file versions without placeholder", &Config{Items: map[string]Vendor{
+			"vendor/files/talos": {Kind: "file", URL: "https://factory.talos.dev/image/metal-amd64.raw.xz", Versions: []string{"v1.11.1", "v1.11.2"}},
+		}}, "url must contain {version}"},
+		{"valid file", &Config{Items: map[string]Vendor{
+			"vendor/files/talos": {Kind: "file", URL: "https://factory.talos.dev/image/{version}/metal-amd64.raw.xz", Versions: []string{"v1.11.2"}, SHA256: map[string]string{
+				"v1.11.2": strings.Repeat("ab", 32),
+			}},
+		}}, ""},
+		{"git destination without owner", &Config{Items: map[string]Vendor{
+			"dex": {Kind: "git", RepoURL: "https://github.com/dexidp/dex", Versions: []string{"v2.43.1"}},
+		}}, "owner/repository"},
+		{"artifact verification", &Config{Items: map[string]Vendor{
+			"vendor/artifacts/policies": {Kind: "artifact", Source: "ghcr.io/example/policies", Versions: []string{"v1"}, Verify: &Verification{
+				Key: "cosign.pub",
+			}},
+		}}, "verify is not supported"},
+		{"registry address with scheme", &Config{Registries: map[string]RegistrySettings{
+			"mirror": {Address: "https://registry.example.com"},
+		}}, "without scheme or path"},
+		{"registry named in-cluster", &Config{Registries: map[string]RegistrySettings{
+			"in-cluster": {Address: "registry.example.com"},
+		}}, "name is reserved"},
+		{"registry with ca file and plain http", &Config{Registries: map[string]RegistrySettings{
+			"mirror": {Address: "registry.example.com", PlainHTTP: true, CAFile: "ca.pem"},
+		}}, "cannot be used with plain_http"},
 	}
 
 	for _, tc := range cases {
@@ -0,0 +1,262 @@
+package vendors
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var envPrefixPattern = regexp.MustCompile(`^[A-Z_][A-Z0-9_]*$`)
+
+// CredentialRef points at upstream registry credentials. Exactly one source is
+// set: a Vault KV v2 path with username and password keys, an environment
+// variable prefix read as <PREFIX>_USERNAME and <PREFIX>_PASSWORD, or a docker
+// config file.
+type CredentialRef struct {
+	Vault        string `yaml:"vault,omitempty"`
+	Env          string `yaml:"env,omitempty"`
+	DockerConfig string `yaml:"docker_config,omitempty"`
+}
+
+// Auth holds resolved credentials for pulling from an upstream source.
+// Username and password credentials are also written to a docker config file
+// or a helm repositories file, so that passwords never appear on the command
+// lines of helm, oras and cosign.
+type Auth struct {
+	Username         string
+	Password         string
+	ConfigFile       string
+	RepositoryConfig string
+}
+
+// helmRepositoryName is the name of the chart repository in RepositoryConfig.
+const helmRepositoryName = "upstream"
+
+type SecretReader interface {
+	ReadSecret(ctx context.Context, path string) (map[string]any, error)
+}
+
+// SourceHost returns the registry or repository host an entry pulls from.
+func (v Vendor) SourceHost() string {
+	switch {
+	case v.Kind == "image" || v.Kind == "artifact":
+		image, err := ParseImageReference(v.Source)
+		if err != nil {
+			return ""
+		}
+		return image.Registry
+	case v.Kind == "file":
+		parsed, err := url.Parse(v.URL)
+		if err != nil {
+			return ""
+		}
+		return parsed.Host
+	case v.Ref != "":
+		host, _, _ := strings.Cut(strings.TrimPrefix(v.Ref, "oci://"), "/")
+		return host
+	default:
+		parsed, err := url.Parse(v.RepoURL)
+		if err != nil {
+			return ""
+		}
+		return parsed.Host
+	}
+}
+
+func (v VendorEntry) NeedsVault() bool {
+	return v.Credentials != nil && v.Credentials.Vault != ""
+}
+
+// ResolveCredentials fills in Auth for every entry with a credential
+// reference, writing username and password credentials to files in dir.
+// secrets is only used for Vault references and may be nil when no entry
+// needs Vault.
+func ResolveCredentials(ctx context.Context, entries []VendorEntry, secrets SecretReader, dir string) error {
+	for i, entry := range entries {
+		ref := entry.Credentials
+		if ref == nil {
+			continue
+		}
+
+		auth, err := resolveCredentialRef(ctx, *ref, secrets)
+		if err != nil {
+			return fmt.Errorf("vendors.%s: resolve credentials: %w", entry.Name, err)
+		}
+		httpChart := entry.Kind == "chart" && entry.Ref == ""
+		if auth.ConfigFile != "" && httpChart {
+			return fmt.Errorf("vendors.%s: docker_config credentials require an OCI source", entry.Name)
+		}
+		switch {
+		case auth.Password == "" || entry.Kind == "file" || entry.Kind == "git":
+			// Files and git repositories are fetched with the credentials
+			// in request headers instead.
+		case httpChart:
+			err = auth.writeRepositoryConfig(dir, entry.RepoURL)
+		default:
+			err = auth.writeDockerConfig(dir, entry.SourceHost())
+		}
+		if err != nil {
+			return fmt.Errorf("vendors.%s: write credentials: %w", entry.Name, err)
+		}
+		entries[i].Auth = auth
+	}
+	return nil
+}
+
+func resolveCredentialRef(ctx context.Context, ref CredentialRef, secrets SecretReader) (*Auth, error) {
+	switch {
+	case ref.DockerConfig != "":
+		if _, err := os.Stat(ref.DockerConfig); err != nil {
+			return nil, fmt.Errorf("docker config: %w", err)
+		}
+		return &Auth{ConfigFile: ref.DockerConfig}, nil
+
+	case ref.Env != "":
+		auth := &Auth{
+			Username: os.Getenv(ref.Env + "_USERNAME"),
+			Password: os.Getenv(ref.Env + "_PASSWORD"),
+		}
+		if auth.Username == "" || auth.Password == "" {
+			return nil, fmt.Errorf("%s_USERNAME and %s_PASSWORD must both be set", ref.Env, ref.Env)
+		}
+		return auth, nil
+
+	default:
+		if secrets == nil {
+			return nil, fmt.Errorf("vault credentials %s require a Vault connection", ref.Vault)
+		}
+		data, err := secrets.ReadSecret(ctx, ref.Vault)
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", ref.Vault, err)
+		}
+		username, _ := data["username"].(string)
+		password, _ := data["password"].(string)
+		if username == "" || password == "" {
+			return nil, fmt.Errorf("%s must contain username and password", ref.Vault)
+		}
+		return &Auth{Username: username, Password: password}, nil
+	}
+}
+
+func validateCredentialRef(context string, ref CredentialRef) error {
+	set := 0
+	for _, value := range []string{ref.Vault, ref.Env, ref.DockerConfig} {
+		if value != "" {
+			set++
+		}
+	}
+	if set != 1 {
+		return fmt.Errorf("%s: exactly one of vault, env or docker_config is required", context)
+	}
+	if ref.Vault != "" {
+		if mount, path, ok := strings.Cut(ref.Vault, "/"); !ok || mount == "" || path == "" {
+			return fmt.Errorf("%s: vault must be in mount/path format, got %q", context, ref.Vault)
+		}
+	}
+	if ref.Env != "" && !envPrefixPattern.MatchString(ref.Env) {
+		return fmt.Errorf("%s: env must be an uppercase variable prefix, got %q", context, ref.Env)
+	}
+	return nil
+}
+
+// writeDockerConfig writes the username and password for host to a docker
+// config file and points ConfigFile at it.
+func (a *Auth) writeDockerConfig(dir, host string) error {
+	entry := map[string]string{"auth": base64.StdEncoding.EncodeToString([]byte(a.Username + ":" + a.Password))}
+	auths := map[string]any{host: entry}
+	// Docker Hub credentials are looked up under the legacy index address.
+	if host == defaultRegistry {
+		auths["https://index.docker.io/v1/"] = entry
+	}
+	path, err := writeCredentialFile(dir, "config.json", map[string]any{"auths": auths})
+	if err != nil {
+		return err
+	}
+	a.ConfigFile = path
+	return nil
+}
+
+// writeRepositoryConfig writes the username and password for an HTTP chart
+// repository to a helm repositories file and points RepositoryConfig at it.
+func (a *Auth) writeRepositoryConfig(dir, repoURL string) error {
+	path, err := writeCredentialFile(dir, "repositories.yaml", map[string]any{
+		"apiVersion": "v1",
+		"repositories": []map[string]string{{
+			"name":     helmRepositoryName,
+			"url":      repoURL,
+			"username": a.Username,
+			"password": a.Password,
+		}},
+	})
+	if err != nil {
+		return err
+	}
+	a.RepositoryConfig = path
+	return nil
+}
+
+// writeCredentialFile writes data as JSON, which helm also reads as YAML, to
+// a new directory in dir that only the current user can read. cosign reads
+// docker config files by directory, so every file gets its own.
+func writeCredentialFile(dir, name string, data any) (string, error) {
+	content, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+	credentialsDir, err := os.MkdirTemp(dir, "credentials-*")
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(credentialsDir, name)
+	if err := os.WriteFile(path, content, 0o600); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// helmArgs returns helm flags for OCI registry credentials.
+func (a *Auth) helmArgs() []string {
+	if a == nil || a.ConfigFile == "" {
+		return nil
+	}
+	return []string{"--registry-config", a.ConfigFile}
+}
+
+// orasArgs returns flags for oras commands that only talk to the source.
+func (a *Auth) orasArgs() []string {
+	if a == nil || a.ConfigFile == "" {
+		return nil
+	}
+	return []string{"--registry-config", a.ConfigFile}
+}
+
+// orasSourceArgs returns oras cp flags for the source side of a copy.
+func (a *Auth) orasSourceArgs() []string {
+	if a == nil || a.ConfigFile == "" {
+		return nil
+	}
+	return []string{"--from-registry-config", a.ConfigFile}
+}
+
+// orasTargetArgs returns oras cp flags for the destination side of a copy.
+func (a *Auth) orasTargetArgs() []string {
+	if a == nil || a.ConfigFile == "" {
+		return nil
+	}
+	return []string{"--to-registry-config", a.ConfigFile}
+}
+
+// cosignEnv points cosign at the docker config directory, since it has no
+// flag for the config file and reads config.json from DOCKER_CONFIG.
+func (a *Auth) cosignEnv() []string {
+	if a == nil || a.ConfigFile == "" {
+		return nil
+	}
+	return []string{"DOCKER_CONFIG=" + filepath.Dir(a.ConfigFile)}
+}
@@ -0,0 +1,131 @@
+package vendors
+
+import (
+	"context"
+	"encoding/base64"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+)
+
+type fakeSecretReader map[string]map[string]any
+
+func (f fakeSecretReader) ReadSecret(_ context.Context, path string) (map[string]any, error) {
+	return f[path], nil
+}
+
+func TestParseAndValidateAppliesHostCredentials(t *testing.T) {
+	config := &Config{
+		Items: map[string]Vendor{
+			"vendor/images/khuedoan/app": {Kind: "image", Source: "code.khuedoan.com/khuedoan/app", Versions: []string{"v1"}},
+			"vendor/images/dexidp/dex":   {Kind: "image", Source: "ghcr.io/dexidp/dex", Versions: []string{"v2.43.1"}},
+		},
+		Credentials: map[string]CredentialRef{
+			"code.khuedoan.com": {Vault: "secret/forgejo/registry"},
+		},
+	}
+
+	entries, err := ParseAndValidate(config)
+	if err != nil {
+		t.Fatalf("unexpected validation error: %v", err)
+	}
+	if entries[0].Credentials != nil {
+		t.Fatalf("expected no credentials for %s, got %+v", entries[0].Name, entries[0].Credentials)
+	}
+	if entries[1].Credentials == nil || entries[1].Credentials.Vault != "secret/forgejo/registry" {
+		t.Fatalf("expected host credentials for %s, got %+v", entries[1].Name, entries[1].Credentials)
+	}
+
+	err = ResolveCredentials(context.Background(), entries, fakeSecretReader{
+		"secret/forgejo/registry": {"username": "khuedoan", "password": "token"},
+	}, t.TempDir())
+	if err != nil {
+		t.Fatalf("unexpected resolve error: %v", err)
+	}
+	if got := entries[1].Auth; got == nil || got.Username != "khuedoan" || got.Password != "token" {
+		t.Fatalf("unexpected auth %+v", got)
+	}
+}
+
+func TestResolveCredentialsFromEnv(t *testing.T) {
+	t.Setenv("DOCKER_HUB_USERNAME", "khuedoan")
+	entries := []VendorEntry{{Name: "vendor/images/library/docker", Vendor: Vendor{
+		Kind: "image", Source: "docker.io/library/docker", Credentials: &CredentialRef{Env: "DOCKER_HUB"},
+	}}}
+
+	dir := t.TempDir()
+	err := ResolveCredentials(context.Background(), entries, nil, dir)
+	if err == nil || !strings.Contains(err.Error(), "DOCKER_HUB_PASSWORD must both be set") {
+		t.Fatalf("expected missing password error, got %v", err)
+	}
+
+	t.Setenv("DOCKER_HUB_PASSWORD", "token")
+	if err := ResolveCredentials(context.Background(), entries, nil, dir); err != nil {
+		t.Fatalf("unexpected resolve error: %v", err)
+	}
+	if got := entries[0].Auth; got.Username != "khuedoan" || got.Password != "token" {
+		t.Fatalf("unexpected auth %+v", got)
+	}
+}
+
+func TestCredentialsStayOffCommandLines(t *testing.T) {
+	t.Setenv("UPSTREAM_USERNAME", "khuedoan")
+	t.Setenv("UPSTREAM_PASSWORD", "hunter2")
+	credentials := &CredentialRef{Env: "UPSTREAM"}
+	entries := []VendorEntry{
+		{Name: "vendor/images/library/docker", Vendor: Vendor{Kind: "image", Source: "docker.io/library/docker", Credentials: credentials}},
+		{Name: "vendor/charts/podinfo", Vendor: Vendor{Kind: "chart", Chart: "podinfo", RepoURL: "https://charts.example.com", Credentials: credentials}},
+	}
+	if err := ResolveCredentials(context.Background(), entries, nil, t.TempDir()); err != nil {
+		t.Fatalf("unexpected resolve error: %v", err)
+	}
+
+	image := entries[0].Auth
+	args := slices.Concat(image.helmArgs(), image.orasArgs(), image.orasSourceArgs(), image.orasTargetArgs(), image.cosignEnv())
+	if strings.Contains(strings.Join(args, " "), "hunter2") {
+		t.Errorf("password in command line %v", args)
+	}
+	config, err := os.ReadFile(image.ConfigFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := base64.StdEncoding.EncodeToString([]byte("khuedoan:hunter2"))
+	for _, host := range []string{`"docker.io"`, `"https://index.docker.io/v1/"`, token} {
+		if !strings.Contains(string(config), host) {
+			t.Errorf("expected %s in docker config %s", host, config)
+		}
+	}
+
+	repositories, err := os.ReadFile(entries[1].Auth.RepositoryConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(repositories), `"url":"https://charts.example.com"`) || !strings.Contains(string(repositories), `"password":"hunter2"`) {
+		t.Errorf("unexpected repositories file %s", repositories)
+	}
+	if entries[1].Auth.ConfigFile != "" {
+		t.Errorf("expected no docker config for an HTTP chart, got %s", entries[1].Auth.ConfigFile)
+	}
+}
+
+func TestCommandErrorsOmitArguments(t *testing.T) {
+	ctx := context.Background()
+	errs := []error{
+		runCommand(ctx, "sh", "-c", "exit 1", "--password", "hunter2"),
+	}
+	_, err := commandOutput(ctx, "sh", "-c", "exit 1", "--password", "hunter2")
+	errs = append(errs, err)
+	for _, err := range errs {
+		if err == nil || strings.Contains(err.Error(), "hunter2") {
+			t.Errorf("expected an error without the password, got %v", err)
+		}
+	}
+}
+
+func TestValidateCredentialRefRequiresOneSource(t *testing.T) {
+	err := validateCredentialRef("vendor_credentials.ghcr.io", CredentialRef{Env: "GHCR", DockerConfig: "config.json"})
+	if err == nil || !strings.Contains(err.Error(), "exactly one of") {
+		t.Fatalf("expected exactly one source error, got %v", err)
+	}
+}
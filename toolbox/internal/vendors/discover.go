@@ -0,0 +1,187 @@
+package vendors
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/charmbracelet/log"
+	"gopkg.in/yaml.v3"
+)
+
+var containerListKeys = []string{"containers", "initContainers", "ephemeralContainers"}
+
+type DiscoverResult struct {
+	// Missing holds new image entries, or existing entries with only the
+	// versions that are not vendored yet.
+	Missing []VendorEntry
+	// Unknown holds internal registry references that no entry vendors, so
+	// their upstream source cannot be inferred.
+	Unknown []string
+}
+
+// Discover renders every vendored chart version and collects the container
+// images it references. When platformDir is set, charts are rendered once per
+// matching HelmRelease with that release's values instead of the defaults.
+func Discover(ctx context.Context, workdir, platformDir string, entries []VendorEntry) (*DiscoverResult, error) {
+	var releases []platformRelease
+	if platformDir != "" {
+		var err error
+		if releases, err = loadPlatformReleases(platformDir); err != nil {
+			return nil, fmt.Errorf("load platform manifests: %w", err)
+		}
+	}
+
+	images := map[string]ImageReference{}
+	for _, chart := range entries {
+		if chart.Kind != "chart" {
+			continue
+		}
+		for _, version := range chart.Versions {
+			valueSets := matchingValues(releases, chart, version)
+			if len(valueSets) == 0 {
+				valueSets = []map[string]any{nil}
+			}
+			for i, values := range valueSets {
+				log.Infof("rendering chart %s@%s", chart.Name, version)
+				manifest, err := renderChart(ctx, filepath.Join(workdir, fmt.Sprintf("values-%d.yaml", i)), chart, version, values)
+				if err != nil {
+					log.Warnf("skipping chart %s@%s: %v", chart.Name, version, err)
+					continue
+				}
+				refs, err := manifestImages(manifest)
+				if err != nil {
+					return nil, fmt.Errorf("parse rendered chart %s@%s: %w", chart.Name, version, err)
+				}
+				for _, image := range refs {
+					images[image.String()] = image
+				}
+			}
+		}
+	}
+
+	return missingImages(entries, slices.Collect(maps.Values(images))), nil
+}
+
+func missingImages(entries []VendorEntry, images []ImageReference) *DiscoverResult {
+	byName := map[string]VendorEntry{}
+	bySource := map[string]VendorEntry{}
+	for _, entry := range entries {
+		if entry.Kind == "image" {
+			byName[entry.Name] = entry
+			bySource[entry.Source] = entry
+		}
+	}
+
+	result := &DiscoverResult{}
+	missing := map[string]*VendorEntry{}
+	for _, image := range images {
+		entry, ok := bySource[image.Source()]
+		if image.Vendored() {
+			entry, ok = byName[image.Repository]
+			if !ok {
+				result.Unknown = append(result.Unknown, image.String())
+				continue
+			}
+		}
+		if !ok {
+			entry = VendorEntry{
+				Name:   imageDestination(image),
+				Vendor: Vendor{Kind: "image", Source: image.Source()},
+			}
+		}
+
+		version := image.Version()
+		if slices.Contains(entry.Versions, version) {
+			continue
+		}
+		item, ok := missing[entry.Name]
+		if !ok {
+			item = &VendorEntry{Name: entry.Name, Vendor: Vendor{Kind: "image", Source: entry.Source}}
+			missing[entry.Name] = item
+		}
+		if !slices.Contains(item.Versions, version) {
+			item.Versions = append(item.Versions, version)
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(missing)) {
+		item := missing[name]
+		slices.Sort(item.Versions)
+		result.Missing = append(result.Missing, *item)
+	}
+	slices.Sort(result.Unknown)
+	return result
+}
+
+func renderChart(ctx context.Context, valuesPath string, chart VendorEntry, version string, values map[string]any) ([]byte, error) {
+	ref, chartArgs, err := chart.helmChartArgs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	args := append([]string{"template", filepath.Base(chart.Name), ref, "--version", version}, chartArgs...)
+	if values != nil {
+		data, err := yaml.Marshal(values)
+		if err != nil {
+			return nil, fmt.Errorf("marshal values: %w", err)
+		}
+		if err := os.WriteFile(valuesPath, data, 0o600); err != nil {
+			return nil, fmt.Errorf("write values: %w", err)
+		}
+		args = append(args, "--values", valuesPath)
+	}
+	return commandOutput(ctx, "helm", args...)
+}
+
+func manifestImages(manifest []byte) ([]ImageReference, error) {
+	var images []ImageReference
+	decoder := yaml.NewDecoder(bytes.NewReader(manifest))
+	for {
+		var document any
+		if err := decoder.Decode(&document); err != nil {
+			if errors.Is(err, io.EOF) {
+				return images, nil
+			}
+			return nil, err
+		}
+		for _, ref := range containerImages(document) {
+			image, err := ParseImageReference(ref)
+			if err != nil {
+				log.Warnf("skipping image: %v", err)
+				continue
+			}
+			images = append(images, image)
+		}
+	}
+}
+
+func containerImages(node any) []string {
+	var images []string
+	switch node := node.(type) {
+	case map[string]any:
+		for key, value := range node {
+			if containers, ok := value.([]any); ok && slices.Contains(containerListKeys, key) {
+				for _, container := range containers {
+					if container, ok := container.(map[string]any); ok {
+						if image, ok := container["image"].(string); ok {
+							images = append(images, image)
+						}
+					}
+				}
+				continue
+			}
+			images = append(images, containerImages(value)...)
+		}
+	case []any:
+		for _, item := range node {
+			images = append(images, containerImages(item)...)
+		}
+	}
+	return images
+}
@@ -0,0 +1,53 @@
+package vendors
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestMissingImages(t *testing.T) {
+	entries := []VendorEntry{
+		{Name: "vendor/images/dexidp/dex", Vendor: Vendor{Kind: "image", Source: "ghcr.io/dexidp/dex", Versions: []string{"v2.43.1"}}},
+	}
+	manifest := []byte(`
+apiVersion: apps/v1
+kind: Deployment
+spec:
+  template:
+    spec:
+      initContainers:
+        - image: busybox:1.37
+      containers:
+        - image: ghcr.io/dexidp/dex:v2.43.1
+        - image: registry.registry.svc.cluster.local/vendor/images/dexidp/dex:v2.44.0
+---
+apiVersion: batch/v1
+kind: CronJob
+spec:
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          containers:
+            - image: registry.registry.svc.cluster.local/vendor/images/unknown:v1
+`)
+
+	images, err := manifestImages(manifest)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	result := missingImages(entries, images)
+
+	want := []VendorEntry{
+		{Name: "vendor/images/busybox", Vendor: Vendor{Kind: "image", Source: "docker.io/library/busybox", Versions: []string{"1.37"}}},
+		{Name: "vendor/images/dexidp/dex", Vendor: Vendor{Kind: "image", Source: "ghcr.io/dexidp/dex", Versions: []string{"v2.44.0"}}},
+	}
+	if !slices.EqualFunc(result.Missing, want, func(a, b VendorEntry) bool {
+		return a.Name == b.Name && a.Source == b.Source && slices.Equal(a.Versions, b.Versions)
+	}) {
+		t.Fatalf("expected missing %+v, got %+v", want, result.Missing)
+	}
+	if !slices.Equal(result.Unknown, []string{InternalRegistry + "/vendor/images/unknown:v1"}) {
+		t.Fatalf("unexpected unknown images %v", result.Unknown)
+	}
+}
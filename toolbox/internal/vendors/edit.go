@@ -0,0 +1,273 @@
+package vendors
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// yamlFile applies small text edits to a YAML file using node positions from
+// yaml.v3, so comments, ordering and formatting around the edits are kept.
+type yamlFile struct {
+	path  string
+	data  []byte
+	root  *yaml.Node
+	edits []textEdit
+}
+
+type textEdit struct {
+	start int
+	end   int
+	text  string
+}
+
+func loadYAMLFile(path string) (*yamlFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read file: %w", err)
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("parse YAML: %w", err)
+	}
+	return &yamlFile{path: path, data: data, root: &root}, nil
+}
+
+func (f *yamlFile) save() error {
+	if len(f.edits) == 0 {
+		return nil
+	}
+
+	// Apply edits back to front so earlier offsets stay valid, and apply
+	// insertions at the same offset in reverse to keep them in order.
+	data := slices.Clone(f.data)
+	edits := slices.Clone(f.edits)
+	slices.Reverse(edits)
+	slices.SortStableFunc(edits, func(a, b textEdit) int { return b.start - a.start })
+	for _, edit := range edits {
+		data = slices.Concat(data[:edit.start], []byte(edit.text), data[edit.end:])
+	}
+
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return fmt.Errorf("stat file: %w", err)
+	}
+	if err := os.WriteFile(f.path, data, info.Mode().Perm()); err != nil {
+		return fmt.Errorf("write file: %w", err)
+	}
+	return nil
+}
+
+// offset converts a 1-based yaml.v3 line and column into a byte offset.
+func (f *yamlFile) offset(line, column int) int {
+	offset := 0
+	for range line - 1 {
+		next := bytes.IndexByte(f.data[offset:], '\n')
+		if next < 0 {
+			return len(f.data)
+		}
+		offset += next + 1
+	}
+	for range column - 1 {
+		if offset >= len(f.data) || f.data[offset] == '\n' {
+			break
+		}
+		_, size := utf8.DecodeRune(f.data[offset:])
+		offset += size
+	}
+	return offset
+}
+
+func (f *yamlFile) replaceScalar(node *yaml.Node, value string) error {
+	start := f.offset(node.Line, node.Column)
+	end, err := f.scalarEnd(node, start)
+	if err != nil {
+		return err
+	}
+
+	text := yamlScalar(value)
+	switch node.Style {
+	case yaml.DoubleQuotedStyle:
+		text = `"` + strings.ReplaceAll(value, `"`, `\"`) + `"`
+	case yaml.SingleQuotedStyle:
+		text = "'" + strings.ReplaceAll(value, "'", "''") + "'"
+	}
+	f.edits = append(f.edits, textEdit{start: start, end: end, text: text})
+	return nil
+}
+
+func (f *yamlFile) scalarEnd(node *yaml.Node, start int) (int, error) {
+	switch node.Style {
+	case 0, yaml.TaggedStyle:
+		end := start + len(node.Value)
+		if end > len(f.data) || string(f.data[start:end]) != node.Value {
+			return 0, fmt.Errorf("%s:%d: cannot locate value %q", f.path, node.Line, node.Value)
+		}
+		return end, nil
+	case yaml.DoubleQuotedStyle:
+		for i := start + 1; i < len(f.data); i++ {
+			switch f.data[i] {
+			case '\\':
+				i++
+			case '"':
+				return i + 1, nil
+			}
+		}
+	case yaml.SingleQuotedStyle:
+		for i := start + 1; i < len(f.data); i++ {
+			if f.data[i] != '\'' {
+				continue
+			}
+			if i+1 < len(f.data) && f.data[i+1] == '\'' {
+				i++
+				continue
+			}
+			return i + 1, nil
+		}
+	}
+	return 0, fmt.Errorf("%s:%d: unsupported scalar style for %q", f.path, node.Line, node.Value)
+}
+
+// insertAfter inserts text at the start of the line following line.
+func (f *yamlFile) insertAfter(line int, text string) {
+	offset := f.offset(line+1, 1)
+	if offset == len(f.data) && len(f.data) > 0 && f.data[len(f.data)-1] != '\n' {
+		text = "\n" + text
+	}
+	f.edits = append(f.edits, textEdit{start: offset, end: offset, text: text})
+}
+
+func (f *yamlFile) document() *yaml.Node {
+	if len(f.root.Content) == 0 {
+		return nil
+	}
+	return f.root.Content[0]
+}
+
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func lastLine(node *yaml.Node) int {
+	line := node.Line
+	for _, child := range node.Content {
+		line = max(line, lastLine(child))
+	}
+	return line
+}
+
+// AppendEntries adds missing versions to existing entries and appends new
+// entries at the end of the vendors section of the settings file.
+func AppendEntries(path string, entries []VendorEntry) error {
+	file, err := loadYAMLFile(path)
+	if err != nil {
+		return err
+	}
+
+	document := file.document()
+	if document == nil || document.Kind != yaml.MappingNode {
+		return fmt.Errorf("%s: expected a YAML mapping", path)
+	}
+	items := mappingValue(document, "vendors")
+
+	var added strings.Builder
+	for _, entry := range entries {
+		existing := mappingValue(items, entry.Name)
+		if existing == nil {
+			added.WriteString(renderEntry(entry))
+			continue
+		}
+
+		versions := mappingValue(existing, "versions")
+		if versions == nil || versions.Kind != yaml.SequenceNode || versions.Style == yaml.FlowStyle || len(versions.Content) == 0 {
+			return fmt.Errorf("%s:%d: vendors.%s: versions must be a block sequence to append to", path, existing.Line, entry.Name)
+		}
+		last := versions.Content[len(versions.Content)-1]
+		indent := strings.Repeat(" ", max(last.Column-3, 0))
+		for _, version := range entry.Versions {
+			file.insertAfter(lastLine(last), indent+"- "+yamlScalar(version)+"\n")
+		}
+	}
+
+	if added.Len() > 0 {
+		switch {
+		case items == nil:
+			file.insertAfter(lastLine(document), "vendors:\n"+added.String())
+		case items.Kind == yaml.MappingNode && items.Style != yaml.FlowStyle:
+			file.insertAfter(lastLine(items), added.String())
+		default:
+			return fmt.Errorf("%s:%d: vendors must be a block mapping to append to", path, items.Line)
+		}
+	}
+
+	return file.save()
+}
+
+func renderEntry(entry VendorEntry) string {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "  %s:\n    kind: %s\n", entry.Name, entry.Kind)
+	if entry.Source != "" {
+		fmt.Fprintf(&builder, "    source: %s\n", yamlScalar(entry.Source))
+	}
+	builder.WriteString("    versions:\n")
+	for _, version := range entry.Versions {
+		fmt.Fprintf(&builder, "      - %s\n", yamlScalar(version))
+	}
+	return builder.String()
+}
+
+// yamlScalar renders value as a plain scalar where possible and quotes it when
+// it would otherwise be parsed as something else, such as "@sha256:...".
+func yamlScalar(value string) string {
+	data, err := yaml.Marshal(value)
+	if err != nil {
+		return value
+	}
+	return strings.TrimSuffix(string(data), "\n")
+}
+
+type VersionUpdate struct {
+	Name string
+	From string
+	To   string
+}
+
+// UpdateVersions replaces versions of existing entries in place.
+func UpdateVersions(path string, updates []VersionUpdate) error {
+	file, err := loadYAMLFile(path)
+	if err != nil {
+		return err
+	}
+	items := mappingValue(file.document(), "vendors")
+
+	for _, update := range updates {
+		versions := mappingValue(mappingValue(items, update.Name), "versions")
+		if versions == nil || versions.Kind != yaml.SequenceNode {
+			return fmt.Errorf("%s: vendors.%s: versions not found", path, update.Name)
+		}
+
+		index := slices.IndexFunc(versions.Content, func(node *yaml.Node) bool { return node.Value == update.From })
+		if index < 0 {
+			return fmt.Errorf("%s: vendors.%s: version %s not found", path, update.Name, update.From)
+		}
+		if err := file.replaceScalar(versions.Content[index], update.To); err != nil {
+			return err
+		}
+	}
+
+	return file.save()
+}
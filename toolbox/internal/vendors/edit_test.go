@@ -0,0 +1,94 @@
+package vendors
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeSettings(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "settings.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func readSettings(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestAppendEntriesKeepsComments(t *testing.T) {
+	path := writeSettings(t, `secrets: {} # unrelated
+vendors:
+  # Dex
+  vendor/images/dexidp/dex:
+    kind: image
+    source: ghcr.io/dexidp/dex
+    versions:
+      - v2.43.1 # current
+`)
+
+	err := AppendEntries(path, []VendorEntry{
+		{Name: "vendor/images/dexidp/dex", Vendor: Vendor{Kind: "image", Versions: []string{"v2.44.0", "@sha256:abc"}}},
+		{Name: "vendor/images/busybox", Vendor: Vendor{Kind: "image", Source: "docker.io/library/busybox", Versions: []string{"1.37"}}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := `secrets: {} # unrelated
+vendors:
+  # Dex
+  vendor/images/dexidp/dex:
+    kind: image
+    source: ghcr.io/dexidp/dex
+    versions:
+      - v2.43.1 # current
+      - v2.44.0
+      - '@sha256:abc'
+  vendor/images/busybox:
+    kind: image
+    source: docker.io/library/busybox
+    versions:
+      - "1.37"
+`
+	if got := readSettings(t, path); got != want {
+		t.Fatalf("unexpected settings file:\n%s", got)
+	}
+}
+
+func TestUpdateVersionsKeepsStyle(t *testing.T) {
+	path := writeSettings(t, `vendors:
+  vendor/charts/dex:
+    kind: chart
+    versions:
+      - 0.23.0 # pinned
+      - "0.22.1"
+`)
+
+	err := UpdateVersions(path, []VersionUpdate{
+		{Name: "vendor/charts/dex", From: "0.23.0", To: "0.24.0"},
+		{Name: "vendor/charts/dex", From: "0.22.1", To: "0.22.2"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := `vendors:
+  vendor/charts/dex:
+    kind: chart
+    versions:
+      - 0.24.0 # pinned
+      - "0.22.2"
+`
+	if got := readSettings(t, path); got != want {
+		t.Fatalf("unexpected settings file:\n%s", got)
+	}
+}
@@ -0,0 +1,160 @@
+package vendors
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	fileArtifactType = "application/vnd.cloudlab.file.v1"
+	fileMediaType    = "application/octet-stream"
+	downloadTimeout  = 30 * time.Minute
+)
+
+func syncFile(ctx context.Context, workdir string, registry Destination, file VendorEntry, version string, result *Result, progress func(int64)) error {
+	result.Source = file.fileURL(version)
+
+	target := fmt.Sprintf("%s/%s:%s", registry.Address, file.Name, RegistryTag(version))
+	if pushed, err := filePushed(ctx, registry, target, file.SHA256[version]); err != nil || pushed {
+		return err
+	}
+
+	filePath, err := downloadFile(ctx, workdir, file, version, progress)
+	if err != nil {
+		return err
+	}
+	if err := pushFile(ctx, filePath, target, registry.orasArgs()...); err != nil {
+		return fmt.Errorf("push file %s@%s: %w", file.Name, version, err)
+	}
+
+	return nil
+}
+
+// filePushed reports whether target holds a file with the given SHA-256,
+// which is the digest of its only layer.
+func filePushed(ctx context.Context, registry Destination, target, checksum string) (bool, error) {
+	if digest, err := registryDigest(ctx, registry, target); err != nil || digest == "" {
+		return false, err
+	}
+	data, err := commandOutput(ctx, "oras", append([]string{"manifest", "fetch", target}, registry.orasArgs()...)...)
+	if err != nil {
+		return false, fmt.Errorf("fetch manifest %s: %w", target, err)
+	}
+	var manifest struct {
+		Layers []struct {
+			Digest string `json:"digest"`
+		} `json:"layers"`
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return false, fmt.Errorf("parse manifest %s: %w", target, err)
+	}
+	return len(manifest.Layers) == 1 && manifest.Layers[0].Digest == "sha256:"+checksum, nil
+}
+
+func (v Vendor) fileURL(version string) string {
+	return strings.ReplaceAll(v.URL, versionPlaceholder, version)
+}
+
+// downloadFile downloads a file version and checks it against the configured
+// SHA-256 digest before anything is pushed. progress, when set, is called with
+// the number of bytes downloaded so far.
+func downloadFile(ctx context.Context, workdir string, file VendorEntry, version string, progress func(int64)) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, downloadTimeout)
+	defer cancel()
+
+	fileURL := file.fileURL(version)
+	dir := filepath.Join(workdir, file.Name, version)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("create file temp dir: %w", err)
+	}
+	filePath := filepath.Join(dir, path.Base(fileURL))
+	// The file is downloaded once and pushed to every destination.
+	if digest, err := fileChecksum(filePath); err == nil && digest == file.SHA256[version] {
+		return filePath, nil
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, fileURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("create request: %w", err)
+	}
+	if file.Auth != nil && file.Auth.Username != "" {
+		request.SetBasicAuth(file.Auth.Username, file.Auth.Password)
+	}
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return "", fmt.Errorf("download %s: %w", fileURL, err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return "", statusError(fileURL, response)
+	}
+
+	output, err := os.Create(filePath)
+	if err != nil {
+		return "", fmt.Errorf("create %s: %w", filePath, err)
+	}
+	defer output.Close()
+
+	hash := sha256.New()
+	var writer io.Writer = io.MultiWriter(output, hash)
+	if progress != nil {
+		writer = &progressWriter{writer: writer, progress: progress}
+	}
+	if _, err := io.Copy(writer, response.Body); err != nil {
+		return "", fmt.Errorf("download %s: %w", fileURL, err)
+	}
+	if err := output.Close(); err != nil {
+		return "", fmt.Errorf("write %s: %w", filePath, err)
+	}
+
+	if digest := hex.EncodeToString(hash.Sum(nil)); digest != file.SHA256[version] {
+		return "", fmt.Errorf("download %s: sha256 mismatch: expected %s, got %s", fileURL, file.SHA256[version], digest)
+	}
+	return filePath, nil
+}
+
+func fileChecksum(filePath string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+type progressWriter struct {
+	writer   io.Writer
+	written  int64
+	progress func(int64)
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	n, err := w.writer.Write(p)
+	w.written += int64(n)
+	w.progress(w.written)
+	return n, err
+}
+
+// pushFile pushes a single file as an OCI artifact to target, which is either
+// a registry reference or an OCI layout depending on targetArgs.
+func pushFile(ctx context.Context, filePath, target string, targetArgs ...string) error {
+	args := []string{"push", target, filepath.Base(filePath) + ":" + fileMediaType, "--artifact-type", fileArtifactType}
+	args = append(args, targetArgs...)
+	// oras push rejects absolute file paths, so run it next to the file.
+	return runCommandIn(ctx, filepath.Dir(filePath), "oras", args...)
+}
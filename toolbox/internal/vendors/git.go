@@ -0,0 +1,198 @@
+package vendors
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// GitRemote is the Forgejo instance git entries are mirrored into. The entry
+// name is the owner/repository in Forgejo.
+type GitRemote struct {
+	URL      string
+	Username string
+	Password string
+	// InternalURL is the in-cluster URL reported as the mirror destination.
+	InternalURL string
+}
+
+func (v VendorEntry) NeedsGit() bool { return v.Kind == "git" }
+
+func syncGit(ctx context.Context, workdir string, remote *GitRemote, repository VendorEntry, version string, result *Result) error {
+	if remote == nil {
+		return fmt.Errorf("mirror %s: no git remote configured", repository.Name)
+	}
+
+	if err := remote.ensureRepository(ctx, repository.Name); err != nil {
+		return fmt.Errorf("create Forgejo repository %s: %w", repository.Name, err)
+	}
+
+	gitDir, err := initGitDir(ctx, workdir, repository.Name)
+	if err != nil {
+		return err
+	}
+	ref, commit, err := fetchGitVersion(ctx, gitDir, repository, version)
+	if err != nil {
+		return err
+	}
+	result.Source = repository.RepoURL + "@" + ref
+	result.Destination = strings.TrimSuffix(remote.InternalURL, "/") + "/" + repository.Name + ".git@" + ref
+	result.Digest = commit
+
+	if err := remote.push(ctx, gitDir, repository.Name, "+"+ref+":"+ref); err != nil {
+		return fmt.Errorf("push %s@%s: %w", repository.Name, version, err)
+	}
+	return nil
+}
+
+// initGitDir creates the bare repository that a git entry is fetched into.
+func initGitDir(ctx context.Context, workdir, name string) (string, error) {
+	gitDir := filepath.Join(workdir, name+".git")
+	if err := os.MkdirAll(gitDir, 0o755); err != nil {
+		return "", fmt.Errorf("create git temp dir: %w", err)
+	}
+	if _, err := commandOutput(ctx, "git", "init", "--bare", "--quiet", gitDir); err != nil {
+		return "", fmt.Errorf("init %s: %w", name, err)
+	}
+	return gitDir, nil
+}
+
+// fetchGitVersion fetches the tag or branch named version into gitDir and
+// returns its full ref name and commit.
+func fetchGitVersion(ctx context.Context, gitDir string, repository VendorEntry, version string) (string, string, error) {
+	sourceEnv := gitAuthEnv(repository.Auth)
+	ref, commit, err := resolveGitRef(ctx, sourceEnv, repository.RepoURL, version)
+	if err != nil {
+		return "", "", fmt.Errorf("resolve %s@%s: %w", repository.Name, version, err)
+	}
+	if _, err := commandOutputEnv(ctx, sourceEnv, "git", "--git-dir", gitDir, "fetch", "--quiet", repository.RepoURL, "+"+ref+":"+ref); err != nil {
+		return "", "", fmt.Errorf("fetch %s@%s: %w", repository.Name, version, err)
+	}
+	return ref, commit, nil
+}
+
+// push pushes refspec from gitDir to the Forgejo repository name.
+func (r *GitRemote) push(ctx context.Context, gitDir, name, refspec string) error {
+	env := gitAuthEnv(&Auth{Username: r.Username, Password: r.Password})
+	destination := strings.TrimSuffix(r.URL, "/") + "/" + name + ".git"
+	_, err := commandOutputEnv(ctx, env, "git", "--git-dir", gitDir, "push", "--quiet", destination, refspec)
+	return err
+}
+
+// resolveGitRef returns the full ref name of a tag or branch, preferring tags,
+// and the commit it points at.
+func resolveGitRef(ctx context.Context, env []string, repoURL, version string) (string, string, error) {
+	output, err := commandOutputEnv(ctx, env, "git", "ls-remote", repoURL, "refs/tags/"+version, "refs/heads/"+version)
+	if err != nil {
+		return "", "", err
+	}
+
+	refs := map[string]string{}
+	for line := range strings.Lines(string(output)) {
+		if fields := strings.Fields(line); len(fields) == 2 {
+			refs[fields[1]] = fields[0]
+		}
+	}
+	for _, ref := range []string{"refs/tags/" + version, "refs/heads/" + version} {
+		if commit, ok := refs[ref]; ok {
+			return ref, commit, nil
+		}
+	}
+	return "", "", fmt.Errorf("no tag or branch named %s", version)
+}
+
+// gitAuthEnv passes credentials as an HTTP header through git's environment
+// configuration, which keeps them out of the remote URL and process list.
+func gitAuthEnv(auth *Auth) []string {
+	if auth == nil || auth.Username == "" {
+		return nil
+	}
+	token := base64.StdEncoding.EncodeToString([]byte(auth.Username + ":" + auth.Password))
+	return []string{
+		"GIT_CONFIG_COUNT=1",
+		"GIT_CONFIG_KEY_0=http.extraHeader",
+		"GIT_CONFIG_VALUE_0=Authorization: Basic " + token,
+		"GIT_TERMINAL_PROMPT=0",
+	}
+}
+
+// ensureRepository creates the repository in Forgejo when it does not exist
+// yet, under the owner organization, which is created when missing, or under
+// the owner user when one exists with that name.
+func (r *GitRemote) ensureRepository(ctx context.Context, name string) error {
+	owner, repository, _ := strings.Cut(name, "/")
+
+	exists, err := r.exists(ctx, "/api/v1/repos/"+owner+"/"+repository)
+	if err != nil || exists {
+		return err
+	}
+
+	org, err := r.exists(ctx, "/api/v1/orgs/"+owner)
+	if err != nil {
+		return err
+	}
+	if !org {
+		user, err := r.exists(ctx, "/api/v1/users/"+owner)
+		if err != nil {
+			return err
+		}
+		if user {
+			return r.post(ctx, "/api/v1/admin/users/"+owner+"/repos", map[string]any{"name": repository})
+		}
+		if err := r.post(ctx, "/api/v1/orgs", map[string]any{"username": owner}); err != nil {
+			return fmt.Errorf("create organization %s: %w", owner, err)
+		}
+	}
+
+	return r.post(ctx, "/api/v1/orgs/"+owner+"/repos", map[string]any{"name": repository})
+}
+
+func (r *GitRemote) exists(ctx context.Context, path string) (bool, error) {
+	response, err := r.request(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return false, err
+	}
+	defer response.Body.Close()
+
+	switch response.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, fmt.Errorf("GET %s: unexpected status %s", path, response.Status)
+	}
+}
+
+func (r *GitRemote) post(ctx context.Context, path string, body map[string]any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	response, err := r.request(ctx, http.MethodPost, path, data)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusCreated {
+		return fmt.Errorf("POST %s: unexpected status %s", path, response.Status)
+	}
+	return nil
+}
+
+func (r *GitRemote) request(ctx context.Context, method, path string, body []byte) (*http.Response, error) {
+	request, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(r.URL, "/")+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	request.SetBasicAuth(r.Username, r.Password)
+	request.Header.Set("Content-Type", "application/json")
+	return http.DefaultClient.Do(request)
+}
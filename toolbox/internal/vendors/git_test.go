@@ -0,0 +1,48 @@
+package vendors
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestEnsureRepository(t *testing.T) {
+	cases := []struct {
+		name     string
+		owner    string
+		existing []string
+		want     []string
+	}{
+		{"organization owner", "vendor", []string{"/api/v1/orgs/vendor", "/api/v1/users/vendor"}, []string{"POST /api/v1/orgs/vendor/repos"}},
+		{"user owner", "khuedoan", []string{"/api/v1/users/khuedoan"}, []string{"POST /api/v1/admin/users/khuedoan/repos"}},
+		{"missing owner", "vendor", nil, []string{"POST /api/v1/orgs", "POST /api/v1/orgs/vendor/repos"}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var posts []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.Method == http.MethodPost:
+					posts = append(posts, r.Method+" "+r.URL.Path)
+					w.WriteHeader(http.StatusCreated)
+				case slices.Contains(tc.existing, r.URL.Path):
+					w.WriteHeader(http.StatusOK)
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			defer server.Close()
+
+			remote := &GitRemote{URL: server.URL, Username: "admin", Password: "password"}
+			if err := remote.ensureRepository(context.Background(), tc.owner+"/dex"); err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(posts, tc.want) {
+				t.Errorf("expected %v, got %v", tc.want, posts)
+			}
+		})
+	}
+}
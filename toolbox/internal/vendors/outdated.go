@@ -0,0 +1,160 @@
+package vendors
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"gopkg.in/yaml.v3"
+)
+
+const indexTimeout = 2 * time.Minute
+
+type Outdated struct {
+	Name    string
+	Kind    string
+	Current string
+	latestVersions
+}
+
+// Target returns the version to update to for the given level (patch, minor
+// or major), or an empty string when there is nothing newer.
+func (o Outdated) Target(level string) string {
+	switch level {
+	case "patch":
+		return o.Patch
+	case "minor":
+		return o.Minor
+	case "major":
+		return o.Major
+	}
+	return ""
+}
+
+func CheckOutdated(ctx context.Context, entries []VendorEntry) ([]Outdated, error) {
+	var outdated []Outdated
+	for _, entry := range entries {
+		// Files have no index of available versions to compare against.
+		if entry.Kind == "file" {
+			continue
+		}
+		log.Infof("checking %s %s", entry.Kind, entry.Name)
+
+		available, err := upstreamVersions(ctx, entry)
+		if err != nil {
+			return nil, fmt.Errorf("list versions for %s: %w", entry.Name, err)
+		}
+
+		for _, current := range entry.Versions {
+			latest, ok := newerVersions(current, available)
+			if !ok {
+				log.Debugf("skipping %s@%s: not a comparable version", entry.Name, current)
+				continue
+			}
+			if latest == (latestVersions{}) {
+				continue
+			}
+			outdated = append(outdated, Outdated{
+				Name:           entry.Name,
+				Kind:           entry.Kind,
+				Current:        current,
+				latestVersions: latest,
+			})
+		}
+	}
+	return outdated, nil
+}
+
+func upstreamVersions(ctx context.Context, entry VendorEntry) ([]string, error) {
+	switch {
+	case entry.Kind == "image" || entry.Kind == "artifact":
+		return registryTags(ctx, entry.Source, entry.Auth)
+	case entry.Kind == "git":
+		return gitTags(ctx, entry.RepoURL, entry.Auth)
+	case entry.Ref != "":
+		tags, err := registryTags(ctx, strings.TrimPrefix(entry.Ref, "oci://"), entry.Auth)
+		if err != nil {
+			return nil, err
+		}
+		// Helm stores "+" in chart versions as "_" in OCI tags.
+		for i, tag := range tags {
+			tags[i] = strings.ReplaceAll(tag, "_", "+")
+		}
+		return tags, nil
+	default:
+		return chartIndexVersions(ctx, entry.RepoURL, entry.Chart, entry.Auth)
+	}
+}
+
+func registryTags(ctx context.Context, repository string, auth *Auth) ([]string, error) {
+	output, err := commandOutput(ctx, "oras", append([]string{"repo", "tags", repository}, auth.orasArgs()...)...)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(string(output)), nil
+}
+
+func chartIndexVersions(ctx context.Context, repoURL, chart string, auth *Auth) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, indexTimeout)
+	defer cancel()
+
+	indexURL := strings.TrimSuffix(repoURL, "/") + "/index.yaml"
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, indexURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	if auth != nil && auth.Username != "" {
+		request.SetBasicAuth(auth.Username, auth.Password)
+	}
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("fetch %s: %w", indexURL, err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, statusError(indexURL, response)
+	}
+
+	data, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", indexURL, err)
+	}
+
+	var index struct {
+		Entries map[string][]struct {
+			Version string `yaml:"version"`
+		} `yaml:"entries"`
+	}
+	if err := yaml.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", indexURL, err)
+	}
+
+	charts, ok := index.Entries[chart]
+	if !ok {
+		return nil, fmt.Errorf("chart %s not found in %s", chart, indexURL)
+	}
+	versions := make([]string, 0, len(charts))
+	for _, chart := range charts {
+		versions = append(versions, chart.Version)
+	}
+	return versions, nil
+}
+
+func gitTags(ctx context.Context, repoURL string, auth *Auth) ([]string, error) {
+	output, err := commandOutputEnv(ctx, gitAuthEnv(auth), "git", "ls-remote", "--tags", "--refs", repoURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var tags []string
+	for line := range strings.Lines(string(output)) {
+		if fields := strings.Fields(line); len(fields) == 2 {
+			tags = append(tags, strings.TrimPrefix(fields[1], "refs/tags/"))
+		}
+	}
+	return tags, nil
+}
@@ -0,0 +1,118 @@
+package vendors
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+type platformObject struct {
+	Kind     string `yaml:"kind"`
+	Metadata struct {
+		Name      string `yaml:"name"`
+		Namespace string `yaml:"namespace"`
+	} `yaml:"metadata"`
+	Spec struct {
+		URL   string `yaml:"url"`
+		Chart struct {
+			Spec struct {
+				Chart     string `yaml:"chart"`
+				Version   string `yaml:"version"`
+				SourceRef struct {
+					Kind string `yaml:"kind"`
+					Name string `yaml:"name"`
+				} `yaml:"sourceRef"`
+			} `yaml:"spec"`
+		} `yaml:"chart"`
+		Values map[string]any `yaml:"values"`
+	} `yaml:"spec"`
+}
+
+type platformRelease struct {
+	Name          string
+	Chart         string
+	Version       string
+	RepositoryURL string
+	Values        map[string]any
+}
+
+func loadPlatformReleases(dir string) ([]platformRelease, error) {
+	objects, err := loadPlatformObjects(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	repositories := map[string]string{}
+	for _, object := range objects {
+		if object.Kind == "HelmRepository" {
+			repositories[object.Metadata.Namespace+"/"+object.Metadata.Name] = object.Spec.URL
+		}
+	}
+
+	var releases []platformRelease
+	for _, object := range objects {
+		chart := object.Spec.Chart.Spec
+		if object.Kind != "HelmRelease" || chart.SourceRef.Kind != "HelmRepository" {
+			continue
+		}
+		releases = append(releases, platformRelease{
+			Name:          object.Metadata.Name,
+			Chart:         chart.Chart,
+			Version:       chart.Version,
+			RepositoryURL: repositories[object.Metadata.Namespace+"/"+chart.SourceRef.Name],
+			Values:        object.Spec.Values,
+		})
+	}
+	return releases, nil
+}
+
+func loadPlatformObjects(dir string) ([]platformObject, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no manifests found in %s", dir)
+	}
+
+	var objects []platformObject
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read file: %w", err)
+		}
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		for {
+			var object platformObject
+			if err := decoder.Decode(&object); err != nil {
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				return nil, fmt.Errorf("parse %s: %w", path, err)
+			}
+			objects = append(objects, object)
+		}
+	}
+	return objects, nil
+}
+
+// matchingValues returns the values of every release that installs the given
+// vendored chart version from the internal registry.
+func matchingValues(releases []platformRelease, chart VendorEntry, version string) []map[string]any {
+	var values []map[string]any
+	for _, release := range releases {
+		if release.RepositoryURL == vendoredChartURL(chart) && release.Version == version {
+			values = append(values, release.Values)
+		}
+	}
+	return values
+}
+
+func vendoredChartURL(chart VendorEntry) string {
+	return "oci://" + InternalRegistry + "/" + chart.Name
+}
@@ -0,0 +1,126 @@
+package vendors
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+)
+
+const vendorPrefix = "vendor/"
+
+type Artifact struct {
+	Repository string
+	Tag        string
+}
+
+func (a Artifact) String() string { return a.Repository + ":" + a.Tag }
+
+// ListUnreferenced returns every tag under the vendor/ prefix of the registry
+// that no entry references anymore and that points at a different manifest
+// than every referenced tag.
+func ListUnreferenced(ctx context.Context, registry Destination, entries []VendorEntry) ([]Artifact, error) {
+	output, err := commandOutput(ctx, "oras", append([]string{"repo", "ls", registry.Address}, registry.orasArgs()...)...)
+	if err != nil {
+		return nil, fmt.Errorf("list repositories: %w", err)
+	}
+
+	tags := map[string][]string{}
+	for _, repository := range strings.Fields(string(output)) {
+		if !strings.HasPrefix(repository, vendorPrefix) {
+			continue
+		}
+		output, err := commandOutput(ctx, "oras", append([]string{"repo", "tags", registry.Address + "/" + repository}, registry.orasArgs()...)...)
+		if err != nil {
+			return nil, fmt.Errorf("list tags for %s: %w", repository, err)
+		}
+		tags[repository] = strings.Fields(string(output))
+	}
+
+	// Deleting a tag deletes the manifest it points at, so the digests of
+	// every tag are needed in repositories with tags to delete.
+	digests := map[string]string{}
+	for _, artifact := range unreferencedArtifacts(entries, tags, nil) {
+		if _, ok := digests[artifact.String()]; ok {
+			continue
+		}
+		for _, tag := range tags[artifact.Repository] {
+			ref := Artifact{Repository: artifact.Repository, Tag: tag}.String()
+			if digests[ref], err = registryDigest(ctx, registry, registry.Address+"/"+ref); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return unreferencedArtifacts(entries, tags, digests), nil
+}
+
+func DeleteArtifact(ctx context.Context, registry Destination, artifact Artifact) error {
+	args := append([]string{"manifest", "delete", "--force", registry.Address + "/" + artifact.String()}, registry.orasArgs()...)
+	if err := runCommand(ctx, "oras", args...); err != nil {
+		return fmt.Errorf("delete %s: %w", artifact, err)
+	}
+	return nil
+}
+
+// RegistryRepository returns the repository an entry is pushed to. Helm
+// appends the chart name to the push target, so charts live one level deeper.
+func RegistryRepository(entry VendorEntry) string {
+	if entry.Kind == "chart" {
+		return entry.Name + "/" + path.Base(entry.pullRef())
+	}
+	return entry.Name
+}
+
+// RegistryTag returns the tag a version is pushed as, or an empty string for
+// versions pinned by digest.
+func RegistryTag(version string) string {
+	if strings.HasPrefix(version, "@") {
+		return ""
+	}
+	return strings.ReplaceAll(version, "+", "_")
+}
+
+// unreferencedArtifacts returns the tags that no entry references. digests
+// maps repository:tag to the digest the tag points at, and tags that share a
+// digest with a referenced tag or a version pinned by digest are kept, as are
+// the cosign tags of those digests.
+func unreferencedArtifacts(entries []VendorEntry, tags map[string][]string, digests map[string]string) []Artifact {
+	referenced := map[string]bool{}
+	kept := map[string]bool{}
+	for _, entry := range entries {
+		if entry.Kind == "git" {
+			continue
+		}
+		repository := RegistryRepository(entry)
+		for _, version := range entry.Versions {
+			if digest, ok := strings.CutPrefix(version, "@"); ok {
+				kept[repository+"@"+digest] = true
+			} else {
+				referenced[repository+":"+RegistryTag(version)] = true
+			}
+		}
+	}
+	for ref, digest := range digests {
+		if referenced[ref] && digest != "" {
+			repository, _, _ := strings.Cut(ref, ":")
+			kept[repository+"@"+digest] = true
+		}
+	}
+
+	var unreferenced []Artifact
+	for _, repository := range slices.Sorted(maps.Keys(tags)) {
+		for _, tag := range slices.Sorted(slices.Values(tags[repository])) {
+			artifact := Artifact{Repository: repository, Tag: tag}
+			if referenced[artifact.String()] || kept[repository+"@"+digests[artifact.String()]] {
+				continue
+			}
+			if digest, ok := cosignTagDigest(tag); ok && kept[repository+"@"+digest] {
+				continue
+			}
+			unreferenced = append(unreferenced, artifact)
+		}
+	}
+	return unreferenced
+}
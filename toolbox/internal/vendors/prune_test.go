@@ -0,0 +1,77 @@
+package vendors
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestUnreferencedArtifacts(t *testing.T) {
+	entries := []VendorEntry{
+		{Name: "vendor/charts/dex", Vendor: Vendor{Kind: "chart", RepoURL: "https://charts.dexidp.io", Chart: "dex", Versions: []string{"0.23.0"}}},
+		{Name: "vendor/charts/app-template", Vendor: Vendor{Kind: "chart", Ref: "oci://ghcr.io/bjw-s-labs/helm/app-template", Versions: []string{"4.6.0+1"}}},
+		{Name: "vendor/images/dexidp/dex", Vendor: Vendor{Kind: "image", Source: "ghcr.io/dexidp/dex", Versions: []string{"v2.43.1"}}},
+	}
+	tags := map[string][]string{
+		"vendor/charts/dex/dex":                   {"0.22.0", "0.23.0"},
+		"vendor/charts/app-template/app-template": {"4.6.0_1"},
+		"vendor/images/dexidp/dex":                {"v2.43.1", "v2.42.0"},
+		"vendor/images/removed":                   {"v1"},
+	}
+
+	got := unreferencedArtifacts(entries, tags, nil)
+	want := []Artifact{
+		{Repository: "vendor/charts/dex/dex", Tag: "0.22.0"},
+		{Repository: "vendor/images/dexidp/dex", Tag: "v2.42.0"},
+		{Repository: "vendor/images/removed", Tag: "v1"},
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
+
+func TestUnreferencedArtifactsKeepsSharedDigests(t *testing.T) {
+	entries := []VendorEntry{
+		{Name: "vendor/images/dexidp/dex", Vendor: Vendor{Kind: "image", Source: "ghcr.io/dexidp/dex", Versions: []string{"v2.43.1", "@sha256:pinned"}}},
+	}
+	tags := map[string][]string{
+		"vendor/images/dexidp/dex": {"v2.43", "v2.43.1", "v2.42.0", "latest"},
+	}
+	digests := map[string]string{
+		"vendor/images/dexidp/dex:v2.43":   "sha256:current",
+		"vendor/images/dexidp/dex:v2.43.1": "sha256:current",
+		"vendor/images/dexidp/dex:v2.42.0": "sha256:old",
+		"vendor/images/dexidp/dex:latest":  "sha256:pinned",
+	}
+
+	got := unreferencedArtifacts(entries, tags, digests)
+	want := []Artifact{{Repository: "vendor/images/dexidp/dex", Tag: "v2.42.0"}}
+	if !slices.Equal(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
+
+func TestUnreferencedArtifactsKeepsCosignTags(t *testing.T) {
+	entries := []VendorEntry{
+		{Name: "vendor/images/dexidp/dex", Vendor: Vendor{Kind: "image", Source: "ghcr.io/dexidp/dex", Versions: []string{"v2.43.1", "@sha256:pinned"}}},
+	}
+	tags := map[string][]string{
+		"vendor/images/dexidp/dex": {"v2.43.1", "v2.42.0", "sha256-current.sig", "sha256-current.att", "sha256-pinned.sbom", "sha256-old.sig"},
+	}
+	digests := map[string]string{
+		"vendor/images/dexidp/dex:v2.43.1":            "sha256:current",
+		"vendor/images/dexidp/dex:v2.42.0":            "sha256:old",
+		"vendor/images/dexidp/dex:sha256-current.sig": "sha256:signature",
+		"vendor/images/dexidp/dex:sha256-current.att": "sha256:attestation",
+		"vendor/images/dexidp/dex:sha256-pinned.sbom": "sha256:sbom",
+		"vendor/images/dexidp/dex:sha256-old.sig":     "sha256:old-signature",
+	}
+
+	got := unreferencedArtifacts(entries, tags, digests)
+	want := []Artifact{
+		{Repository: "vendor/images/dexidp/dex", Tag: "sha256-old.sig"},
+		{Repository: "vendor/images/dexidp/dex", Tag: "v2.42.0"},
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,76 @@
+package vendors
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	InternalRegistry = "registry.registry.svc.cluster.local"
+	defaultRegistry  = "docker.io"
+)
+
+type ImageReference struct {
+	Registry   string
+	Repository string
+	Tag        string
+	Digest     string
+}
+
+func ParseImageReference(ref string) (ImageReference, error) {
+	original := strings.TrimSpace(ref)
+	if original == "" || strings.Contains(original, "://") {
+		return ImageReference{}, fmt.Errorf("invalid image reference %q", original)
+	}
+
+	ref = original
+	var image ImageReference
+	if name, digest, ok := strings.Cut(ref, "@"); ok {
+		ref, image.Digest = name, digest
+	}
+	if i := strings.LastIndex(ref, ":"); i > strings.LastIndex(ref, "/") {
+		ref, image.Tag = ref[:i], ref[i+1:]
+	}
+
+	first, rest, ok := strings.Cut(ref, "/")
+	if ok && (strings.ContainsAny(first, ".:") || first == "localhost") {
+		image.Registry, image.Repository = first, rest
+	} else {
+		image.Registry, image.Repository = defaultRegistry, ref
+	}
+	if image.Registry == defaultRegistry && !strings.Contains(image.Repository, "/") {
+		image.Repository = "library/" + image.Repository
+	}
+
+	if image.Repository == "" || strings.ToLower(image.Repository) != image.Repository {
+		return ImageReference{}, fmt.Errorf("invalid image reference %q", original)
+	}
+	if image.Tag == "" && image.Digest == "" {
+		image.Tag = "latest"
+	}
+	return image, nil
+}
+
+func (r ImageReference) Source() string { return r.Registry + "/" + r.Repository }
+
+// Version returns the reference in the same form as Vendor.Versions, which
+// pins digests as "@sha256:..." and otherwise uses the tag.
+func (r ImageReference) Version() string {
+	if r.Digest != "" {
+		return "@" + r.Digest
+	}
+	return r.Tag
+}
+
+func (r ImageReference) Vendored() bool { return r.Registry == InternalRegistry }
+
+func (r ImageReference) String() string {
+	if r.Digest != "" {
+		return r.Source() + "@" + r.Digest
+	}
+	return r.Source() + ":" + r.Tag
+}
+
+func imageDestination(image ImageReference) string {
+	return "vendor/images/" + strings.TrimPrefix(image.Repository, "library/")
+}
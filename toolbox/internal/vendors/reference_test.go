@@ -0,0 +1,40 @@
+package vendors
+
+import "testing"
+
+func TestParseImageReference(t *testing.T) {
+	cases := []struct {
+		ref     string
+		want    ImageReference
+		version string
+	}{
+		{"nginx", ImageReference{Registry: "docker.io", Repository: "library/nginx", Tag: "latest"}, "latest"},
+		{"otel/opentelemetry-collector-contrib:0.146.1", ImageReference{Registry: "docker.io", Repository: "otel/opentelemetry-collector-contrib", Tag: "0.146.1"}, "0.146.1"},
+		{"ghcr.io/dexidp/dex:v2.43.1", ImageReference{Registry: "ghcr.io", Repository: "dexidp/dex", Tag: "v2.43.1"}, "v2.43.1"},
+		{"localhost:5000/app@sha256:abc", ImageReference{Registry: "localhost:5000", Repository: "app", Digest: "sha256:abc"}, "@sha256:abc"},
+		{"registry.registry.svc.cluster.local/vendor/images/dexidp/dex:v2.43.1", ImageReference{Registry: InternalRegistry, Repository: "vendor/images/dexidp/dex", Tag: "v2.43.1"}, "v2.43.1"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.ref, func(t *testing.T) {
+			got, err := ParseImageReference(tc.ref)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tc.want {
+				t.Fatalf("expected %+v, got %+v", tc.want, got)
+			}
+			if got.Version() != tc.version {
+				t.Fatalf("expected version %q, got %q", tc.version, got.Version())
+			}
+		})
+	}
+}
+
+func TestParseImageReferenceRejectsInvalid(t *testing.T) {
+	for _, ref := range []string{"", "oci://ghcr.io/dexidp/dex", "ghcr.io/DexIDP/dex:v1"} {
+		if _, err := ParseImageReference(ref); err == nil {
+			t.Fatalf("expected error for %q, got nil", ref)
+		}
+	}
+}
@@ -0,0 +1,418 @@
+package vendors
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var imageKeys = []string{"image", "imageName"}
+
+// RefIssue is a chart, image or artifact reference in the platform manifests
+// that does not match the vendor entries.
+type RefIssue struct {
+	File    string
+	Line    int
+	Message string
+	Fixed   bool
+
+	fix func() error
+}
+
+func (i RefIssue) String() string {
+	return fmt.Sprintf("%s:%d: %s", i.File, i.Line, i.Message)
+}
+
+// notFixable is returned by fixes that cannot be applied safely, which leaves
+// the issue for a manual fix.
+type notFixable struct {
+	reason string
+}
+
+func (e notFixable) Error() string { return e.reason }
+
+type manifestDocument struct {
+	file *yamlFile
+	node *yaml.Node
+}
+
+type manifestRepository struct {
+	manifestDocument
+	url *yaml.Node
+	// charts are the charts installed from this repository, which can only
+	// be pointed at a vendored chart when it serves a single chart.
+	charts map[string]bool
+	fixed  bool
+}
+
+// refChecker checks the manifests of one platform environment. Flux objects
+// reference HelmRepositories by namespace and name, so environments cannot be
+// checked together.
+type refChecker struct {
+	byName       map[string]VendorEntry
+	bySource     map[string]VendorEntry
+	repositories map[string]*manifestRepository
+	issues       []RefIssue
+}
+
+// CheckRefs reports references in the manifests of dir that are pulled from
+// an upstream registry although a vendored equivalent exists, and vendored
+// references to versions that are not vendored. With fix, upstream references
+// whose version is vendored are rewritten to the internal registry.
+func CheckRefs(dir string, entries []VendorEntry, fix bool) ([]RefIssue, error) {
+	documents, err := loadManifestDocuments(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	checker := &refChecker{
+		byName:       map[string]VendorEntry{},
+		bySource:     map[string]VendorEntry{},
+		repositories: map[string]*manifestRepository{},
+	}
+	for _, entry := range entries {
+		checker.byName[entry.Name] = entry
+		switch entry.Kind {
+		case "image":
+			if image, err := ParseImageReference(entry.Source); err == nil {
+				checker.bySource["image:"+image.Source()] = entry
+			}
+		case "artifact":
+			checker.bySource["artifact:"+entry.Source] = entry
+		case "chart":
+			if entry.Ref != "" {
+				checker.bySource["chart:"+entry.Ref] = entry
+			} else {
+				checker.bySource["chart:"+strings.TrimSuffix(entry.RepoURL, "/")+"/"+entry.Chart] = entry
+			}
+		}
+	}
+
+	for _, document := range documents {
+		if documentKind(document.node) == "HelmRepository" {
+			checker.addRepository(document)
+		}
+	}
+	for _, document := range documents {
+		switch documentKind(document.node) {
+		case "HelmRelease":
+			checker.checkRelease(document)
+		case "OCIRepository":
+			checker.checkOCIRepository(document)
+		}
+		checker.checkImages(document, document.node)
+	}
+
+	if !fix {
+		return checker.issues, nil
+	}
+
+	for i := range checker.issues {
+		issue := &checker.issues[i]
+		if issue.fix == nil {
+			continue
+		}
+		var skip notFixable
+		if err := issue.fix(); errors.As(err, &skip) {
+			issue.Message += " (not fixed: " + skip.reason + ")"
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		issue.Fixed = true
+	}
+
+	saved := map[*yamlFile]bool{}
+	for _, document := range documents {
+		if saved[document.file] {
+			continue
+		}
+		saved[document.file] = true
+		if err := document.file.save(); err != nil {
+			return nil, fmt.Errorf("%s: %w", document.file.path, err)
+		}
+	}
+	return checker.issues, nil
+}
+
+func loadManifestDocuments(dir string) ([]manifestDocument, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no manifests found in %s", dir)
+	}
+
+	var documents []manifestDocument
+	for _, path := range paths {
+		file, err := loadYAMLFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		decoder := yaml.NewDecoder(bytes.NewReader(file.data))
+		for {
+			var node yaml.Node
+			if err := decoder.Decode(&node); err != nil {
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				return nil, fmt.Errorf("parse %s: %w", path, err)
+			}
+			if len(node.Content) > 0 {
+				documents = append(documents, manifestDocument{file: file, node: node.Content[0]})
+			}
+		}
+	}
+	return documents, nil
+}
+
+func documentKind(node *yaml.Node) string {
+	if kind := mappingValue(node, "kind"); kind != nil {
+		return kind.Value
+	}
+	return ""
+}
+
+func (c *refChecker) report(document manifestDocument, node *yaml.Node, fix func() error, format string, args ...any) {
+	c.issues = append(c.issues, RefIssue{
+		File:    document.file.path,
+		Line:    node.Line,
+		Message: fmt.Sprintf(format, args...),
+		fix:     fix,
+	})
+}
+
+func (c *refChecker) addRepository(document manifestDocument) {
+	name := mappingValue(mappingValue(document.node, "metadata"), "name")
+	url := mappingValue(mappingValue(document.node, "spec"), "url")
+	if name == nil || url == nil {
+		return
+	}
+	namespace := ""
+	if value := mappingValue(mappingValue(document.node, "metadata"), "namespace"); value != nil {
+		namespace = value.Value
+	}
+	c.repositories[namespace+"/"+name.Value] = &manifestRepository{
+		manifestDocument: document,
+		url:              url,
+		charts:           map[string]bool{},
+	}
+
+	if name, ok := vendoredName(url.Value); ok {
+		if entry, ok := c.byName[name]; !ok || entry.Kind != "chart" {
+			c.report(document, url, nil, "%s is not vendored by any chart entry", url.Value)
+		}
+	}
+}
+
+func (c *refChecker) checkRelease(document manifestDocument) {
+	chartSpec := mappingValue(mappingValue(mappingValue(document.node, "spec"), "chart"), "spec")
+	chart := mappingValue(chartSpec, "chart")
+	version := mappingValue(chartSpec, "version")
+	sourceRef := mappingValue(chartSpec, "sourceRef")
+	if chart == nil || version == nil || mappingValue(sourceRef, "kind") == nil || mappingValue(sourceRef, "kind").Value != "HelmRepository" {
+		return
+	}
+	// sourceRef defaults to the namespace of the HelmRelease.
+	sourceName := mappingValue(sourceRef, "name")
+	namespace := mappingValue(sourceRef, "namespace")
+	if namespace == nil {
+		namespace = mappingValue(mappingValue(document.node, "metadata"), "namespace")
+	}
+	if sourceName == nil || namespace == nil {
+		return
+	}
+	repository, ok := c.repositories[namespace.Value+"/"+sourceName.Value]
+	if !ok {
+		return
+	}
+
+	repository.charts[chart.Value] = true
+
+	if name, ok := vendoredName(repository.url.Value); ok {
+		if entry, ok := c.byName[name]; ok && entry.Kind == "chart" && !slices.Contains(entry.Versions, version.Value) {
+			c.report(document, version, nil, "chart %s version %s is not vendored (vendored: %s)", name, version.Value, strings.Join(entry.Versions, ", "))
+		}
+		return
+	}
+
+	entry, ok := c.bySource["chart:"+strings.TrimSuffix(repository.url.Value, "/")+"/"+chart.Value]
+	if !ok {
+		return
+	}
+	if !slices.Contains(entry.Versions, version.Value) {
+		c.report(document, version, nil, "chart %s is vendored as %s, but not version %s", chart.Value, entry.Name, version.Value)
+		return
+	}
+	c.report(document, chart, func() error { return c.fixRepository(repository, entry) },
+		"chart %s from %s is vendored as %s", chart.Value, repository.url.Value, vendoredChartURL(entry))
+}
+
+// fixRepository points a HelmRepository at the vendored chart, unless other
+// charts are installed from it too.
+func (c *refChecker) fixRepository(repository *manifestRepository, entry VendorEntry) error {
+	if repository.fixed {
+		return nil
+	}
+	if len(repository.charts) != 1 {
+		return notFixable{reason: "the HelmRepository serves more than one chart"}
+	}
+	repository.fixed = true
+
+	spec := mappingValue(repository.node, "spec")
+	if err := repository.file.replaceScalar(repository.url, vendoredChartURL(entry)); err != nil {
+		return err
+	}
+	indent := strings.Repeat(" ", spec.Column-1)
+	if mappingValue(spec, "type") == nil {
+		repository.file.insertAfter(repository.url.Line, indent+"type: oci\n")
+	}
+	if mappingValue(spec, "insecure") == nil {
+		repository.file.insertAfter(repository.url.Line, indent+"insecure: true\n")
+	}
+	return nil
+}
+
+func (c *refChecker) checkOCIRepository(document manifestDocument) {
+	spec := mappingValue(document.node, "spec")
+	url := mappingValue(spec, "url")
+	tag := mappingValue(mappingValue(spec, "ref"), "tag")
+	if url == nil {
+		return
+	}
+
+	if name, ok := vendoredName(url.Value); ok {
+		entry, ok := c.byName[name]
+		switch {
+		case !ok || entry.Kind != "artifact":
+			c.report(document, url, nil, "%s is not vendored by any artifact entry", url.Value)
+		case tag != nil && !slices.Contains(entry.Versions, tag.Value):
+			c.report(document, tag, nil, "artifact %s version %s is not vendored (vendored: %s)", name, tag.Value, strings.Join(entry.Versions, ", "))
+		}
+		return
+	}
+
+	entry, ok := c.bySource["artifact:"+strings.TrimPrefix(url.Value, "oci://")]
+	if !ok {
+		return
+	}
+	if tag == nil || !slices.Contains(entry.Versions, tag.Value) {
+		c.report(document, url, nil, "artifact %s is vendored as %s, but not this version", url.Value, entry.Name)
+		return
+	}
+	c.report(document, url, func() error {
+		if err := document.file.replaceScalar(url, "oci://"+InternalRegistry+"/"+entry.Name); err != nil {
+			return err
+		}
+		if mappingValue(spec, "insecure") == nil {
+			document.file.insertAfter(url.Line, strings.Repeat(" ", spec.Column-1)+"insecure: true\n")
+		}
+		return nil
+	}, "artifact %s is vendored as %s", url.Value, entry.Name)
+}
+
+// checkImages walks a manifest for "image: <ref>" strings and image mappings
+// with a repository and tag, as used in most chart values.
+func (c *refChecker) checkImages(document manifestDocument, node *yaml.Node) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if !slices.Contains(imageKeys, key.Value) {
+				c.checkImages(document, value)
+				continue
+			}
+			switch value.Kind {
+			case yaml.ScalarNode:
+				c.checkImageString(document, value)
+			case yaml.MappingNode:
+				c.checkImageMapping(document, value)
+			}
+		}
+	case yaml.SequenceNode:
+		for _, item := range node.Content {
+			c.checkImages(document, item)
+		}
+	}
+}
+
+func (c *refChecker) checkImageString(document manifestDocument, node *yaml.Node) {
+	image, err := ParseImageReference(node.Value)
+	if err != nil {
+		return
+	}
+	c.checkImage(document, node, image, func(entry VendorEntry) error {
+		if node.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0 {
+			return notFixable{reason: "block scalars are not rewritten"}
+		}
+		vendored := ImageReference{Registry: InternalRegistry, Repository: entry.Name, Tag: image.Tag, Digest: image.Digest}
+		return document.file.replaceScalar(node, vendored.String())
+	})
+}
+
+func (c *refChecker) checkImageMapping(document manifestDocument, node *yaml.Node) {
+	repository := mappingValue(node, "repository")
+	tag := mappingValue(node, "tag")
+	registry := mappingValue(node, "registry")
+	if repository == nil || repository.Kind != yaml.ScalarNode || tag == nil || tag.Kind != yaml.ScalarNode {
+		return
+	}
+
+	ref := repository.Value + ":" + tag.Value
+	if registry != nil && registry.Value != "" {
+		ref = registry.Value + "/" + ref
+	}
+	image, err := ParseImageReference(ref)
+	if err != nil {
+		return
+	}
+	c.checkImage(document, repository, image, func(entry VendorEntry) error {
+		if registry != nil && registry.Value != "" {
+			if err := document.file.replaceScalar(registry, InternalRegistry); err != nil {
+				return err
+			}
+			return document.file.replaceScalar(repository, entry.Name)
+		}
+		return document.file.replaceScalar(repository, InternalRegistry+"/"+entry.Name)
+	})
+}
+
+func (c *refChecker) checkImage(document manifestDocument, node *yaml.Node, image ImageReference, fix func(VendorEntry) error) {
+	version := image.Version()
+	if image.Vendored() {
+		entry, ok := c.byName[image.Repository]
+		switch {
+		case !ok || entry.Kind != "image":
+			c.report(document, node, nil, "image %s is not vendored by any image entry", image)
+		case !slices.Contains(entry.Versions, version):
+			c.report(document, node, nil, "image %s version %s is not vendored (vendored: %s)", entry.Name, version, strings.Join(entry.Versions, ", "))
+		}
+		return
+	}
+
+	entry, ok := c.bySource["image:"+image.Source()]
+	if !ok {
+		return
+	}
+	if !slices.Contains(entry.Versions, version) {
+		c.report(document, node, nil, "image %s is vendored as %s, but not version %s", image.Source(), entry.Name, version)
+		return
+	}
+	c.report(document, node, func() error { return fix(entry) }, "image %s is vendored as %s", image, entry.Name)
+}
+
+// vendoredName returns the vendor entry name of an internal registry URL.
+func vendoredName(url string) (string, bool) {
+	name, ok := strings.CutPrefix(strings.TrimPrefix(url, "oci://"), InternalRegistry+"/")
+	if !ok || !strings.HasPrefix(name, vendorPrefix) {
+		return "", false
+	}
+	return name, true
+}
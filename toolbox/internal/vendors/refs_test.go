@@ -0,0 +1,109 @@
+package vendors
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeManifest(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheckRefsFix(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dex.yaml")
+	writeManifest(t, path, `apiVersion: source.toolkit.fluxcd.io/v1
+kind: HelmRepository
+metadata:
+  name: dex
+  namespace: flux-system
+spec:
+  interval: 1h
+  url: https://charts.dexidp.io
+---
+apiVersion: helm.toolkit.fluxcd.io/v2
+kind: HelmRelease
+metadata:
+  name: dex
+  namespace: flux-system
+spec:
+  chart:
+    spec:
+      chart: dex
+      version: 0.23.0
+      sourceRef:
+        kind: HelmRepository
+        name: dex
+  values:
+    image:
+      repository: ghcr.io/dexidp/dex # upstream
+      tag: v2.43.1
+    sidecar:
+      image: "ghcr.io/dexidp/dex:v2.42.0"
+    proxy:
+      image: registry.registry.svc.cluster.local/vendor/images/nginx:1.29
+`)
+	entries := []VendorEntry{
+		{Name: "vendor/charts/dex", Vendor: Vendor{Kind: "chart", RepoURL: "https://charts.dexidp.io", Chart: "dex", Versions: []string{"0.23.0"}}},
+		{Name: "vendor/images/dexidp/dex", Vendor: Vendor{Kind: "image", Source: "ghcr.io/dexidp/dex", Versions: []string{"v2.43.1"}}},
+		{Name: "vendor/images/nginx", Vendor: Vendor{Kind: "image", Source: "nginx", Versions: []string{"1.28"}}},
+	}
+
+	issues, err := CheckRefs(filepath.Dir(path), entries, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		line  int
+		fixed bool
+	}{{18, true}, {25, true}, {28, false}, {30, false}}
+	if len(issues) != len(want) {
+		t.Fatalf("expected %d issues, got %v", len(want), issues)
+	}
+	for i, issue := range issues {
+		if issue.Line != want[i].line || issue.Fixed != want[i].fixed {
+			t.Errorf("expected issue on line %d with fixed=%v, got %s (fixed=%v)", want[i].line, want[i].fixed, issue, issue.Fixed)
+		}
+	}
+
+	wantManifest := `apiVersion: source.toolkit.fluxcd.io/v1
+kind: HelmRepository
+metadata:
+  name: dex
+  namespace: flux-system
+spec:
+  interval: 1h
+  url: oci://registry.registry.svc.cluster.local/vendor/charts/dex
+  type: oci
+  insecure: true
+---
+apiVersion: helm.toolkit.fluxcd.io/v2
+kind: HelmRelease
+metadata:
+  name: dex
+  namespace: flux-system
+spec:
+  chart:
+    spec:
+      chart: dex
+      version: 0.23.0
+      sourceRef:
+        kind: HelmRepository
+        name: dex
+  values:
+    image:
+      repository: registry.registry.svc.cluster.local/vendor/images/dexidp/dex # upstream
+      tag: v2.43.1
+    sidecar:
+      image: "ghcr.io/dexidp/dex:v2.42.0"
+    proxy:
+      image: registry.registry.svc.cluster.local/vendor/images/nginx:1.29
+`
+	if got := readSettings(t, path); got != wantManifest {
+		t.Fatalf("unexpected manifest:\n%s", got)
+	}
+}
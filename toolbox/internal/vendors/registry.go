@@ -0,0 +1,183 @@
+package vendors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/khuedoan/cloudlab/toolbox/internal/settings"
+)
+
+// InClusterRegistry names the in-cluster registry, which is reached through a
+// port-forward and does not need a registries entry.
+const InClusterRegistry = "in-cluster"
+
+// RegistrySettings is an external registry that vendored artifacts can be
+// pushed to in addition to, or instead of, the in-cluster registry.
+type RegistrySettings struct {
+	Address     string         `yaml:"address"`
+	CAFile      string         `yaml:"ca_file,omitempty"`
+	PlainHTTP   bool           `yaml:"plain_http,omitempty"`
+	Credentials *CredentialRef `yaml:"credentials,omitempty"`
+}
+
+// Destination is a registry that vendored artifacts are pushed to.
+type Destination struct {
+	Name string
+	// Address is the host and port the registry is reached at from here,
+	// which for the in-cluster registry is the local end of a port-forward.
+	Address string
+	// PullAddress is the host workloads pull from.
+	PullAddress string
+	PlainHTTP   bool
+	CAFile      string
+	Credentials *CredentialRef
+	Auth        *Auth
+}
+
+// LoadDestinations returns the registries selected by name. The in-cluster
+// registry is returned without an address, which the caller fills in once
+// the port-forward is up.
+func LoadDestinations(configPath, env string, names []string) ([]Destination, error) {
+	config := &Config{}
+	if slices.ContainsFunc(names, func(name string) bool { return name != InClusterRegistry }) {
+		var err error
+		if config, err = LoadConfig(configPath, env); err != nil {
+			return nil, fmt.Errorf("load settings file: %w", err)
+		}
+	}
+	if err := validateRegistries(config.Registries); err != nil {
+		return nil, fmt.Errorf("validate settings: %w", err)
+	}
+
+	var destinations []Destination
+	for _, name := range names {
+		if slices.ContainsFunc(destinations, func(destination Destination) bool { return destination.Name == name }) {
+			continue
+		}
+		if name == InClusterRegistry {
+			destinations = append(destinations, Destination{Name: name, PullAddress: InternalRegistry, PlainHTTP: true})
+			continue
+		}
+
+		registry, ok := config.Registries[name]
+		if !ok {
+			return nil, fmt.Errorf("registry %q not found in registries (available: %s)", name, strings.Join(registryNames(config.Registries), ", "))
+		}
+		destinations = append(destinations, Destination{
+			Name:        name,
+			Address:     registry.Address,
+			PullAddress: registry.Address,
+			PlainHTTP:   registry.PlainHTTP,
+			CAFile:      registry.CAFile,
+			Credentials: registry.Credentials,
+		})
+	}
+	return destinations, nil
+}
+
+func registryNames(registries map[string]RegistrySettings) []string {
+	names := []string{InClusterRegistry}
+	for name := range registries {
+		names = append(names, name)
+	}
+	slices.Sort(names[1:])
+	return names
+}
+
+func validateRegistries(registries map[string]RegistrySettings) error {
+	var errs []error
+	for _, name := range registryNames(registries)[1:] {
+		if err := validateRegistry(name, registries[name]); err != nil {
+			errs = append(errs, settings.AtKeys(err, "registries", name))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func validateRegistry(name string, registry RegistrySettings) error {
+	switch {
+	case name == InClusterRegistry:
+		return fmt.Errorf("registries.%s: name is reserved for the in-cluster registry", name)
+	case registry.Address == "":
+		return fmt.Errorf("registries.%s: address is required", name)
+	case strings.Contains(registry.Address, "://") || strings.Contains(registry.Address, "/"):
+		return fmt.Errorf("registries.%s: address must be a host[:port] without scheme or path", name)
+	case registry.PlainHTTP && registry.CAFile != "":
+		return fmt.Errorf("registries.%s: ca_file cannot be used with plain_http", name)
+	}
+	if registry.Credentials != nil {
+		return validateCredentialRef("registries."+name+".credentials", *registry.Credentials)
+	}
+	return nil
+}
+
+func (d Destination) NeedsVault() bool {
+	return d.Credentials != nil && d.Credentials.Vault != ""
+}
+
+// ResolveDestinationCredentials fills in Auth for every destination with a
+// credential reference, writing username and password credentials to files in
+// dir, and checks that CA files exist.
+func ResolveDestinationCredentials(ctx context.Context, destinations []Destination, secrets SecretReader, dir string) error {
+	for i, destination := range destinations {
+		if destination.CAFile != "" {
+			if _, err := os.Stat(destination.CAFile); err != nil {
+				return fmt.Errorf("registries.%s: ca_file: %w", destination.Name, err)
+			}
+		}
+		if destination.Credentials == nil {
+			continue
+		}
+		auth, err := resolveCredentialRef(ctx, *destination.Credentials, secrets)
+		if err != nil {
+			return fmt.Errorf("registries.%s: resolve credentials: %w", destination.Name, err)
+		}
+		if auth.Password != "" {
+			if err := auth.writeDockerConfig(dir, destination.Address); err != nil {
+				return fmt.Errorf("registries.%s: write credentials: %w", destination.Name, err)
+			}
+		}
+		destinations[i].Auth = auth
+	}
+	return nil
+}
+
+// orasArgs returns flags for oras commands that only talk to the destination.
+func (d Destination) orasArgs() []string {
+	args := d.Auth.orasArgs()
+	switch {
+	case d.PlainHTTP:
+		args = append(args, "--plain-http")
+	case d.CAFile != "":
+		args = append(args, "--ca-file", d.CAFile)
+	}
+	return args
+}
+
+// orasTargetArgs returns oras cp flags for the destination side of a copy.
+func (d Destination) orasTargetArgs() []string {
+	args := d.Auth.orasTargetArgs()
+	switch {
+	case d.PlainHTTP:
+		args = append(args, "--to-plain-http")
+	case d.CAFile != "":
+		args = append(args, "--to-ca-file", d.CAFile)
+	}
+	return args
+}
+
+// helmPushArgs returns helm push flags for the destination.
+func (d Destination) helmPushArgs() []string {
+	args := d.Auth.helmArgs()
+	switch {
+	case d.PlainHTTP:
+		args = append(args, "--plain-http")
+	case d.CAFile != "":
+		args = append(args, "--ca-file", d.CAFile)
+	}
+	return args
+}
@@ -0,0 +1,66 @@
+package vendors
+
+import (
+	"context"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLoadDestinations(t *testing.T) {
+	path := writeSettings(t, `registries:
+  mirror:
+    address: registry.example.com:5000
+    plain_http: true
+vendors: {}
+`)
+
+	destinations, err := LoadDestinations(path, "", []string{"mirror", InClusterRegistry, "mirror"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(destinations) != 2 {
+		t.Fatalf("expected 2 destinations, got %+v", destinations)
+	}
+	if got := destinations[0]; got.Name != "mirror" || got.Address != "registry.example.com:5000" || got.PullAddress != got.Address || !got.PlainHTTP {
+		t.Errorf("unexpected mirror destination %+v", got)
+	}
+	if got := destinations[1]; got.Name != InClusterRegistry || got.Address != "" || got.PullAddress != InternalRegistry {
+		t.Errorf("unexpected in-cluster destination %+v", got)
+	}
+
+	if _, err := LoadDestinations(path, "", []string{"backup"}); err == nil || !strings.Contains(err.Error(), "available: in-cluster, mirror") {
+		t.Fatalf("expected unknown registry error, got %v", err)
+	}
+}
+
+func TestDestinationArgs(t *testing.T) {
+	destination := Destination{Name: "mirror", CAFile: "ca.pem", Auth: &Auth{ConfigFile: "config.json"}}
+	want := []string{"--to-registry-config", "config.json", "--to-ca-file", "ca.pem"}
+	if got := destination.orasTargetArgs(); strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("orasTargetArgs() = %v, want %v", got, want)
+	}
+}
+
+func TestResolveDestinationCredentials(t *testing.T) {
+	t.Setenv("MIRROR_USERNAME", "user")
+	t.Setenv("MIRROR_PASSWORD", "hunter2")
+	destinations := []Destination{{Name: "mirror", Address: "registry.example.com:5000", Credentials: &CredentialRef{Env: "MIRROR"}}}
+	if err := ResolveDestinationCredentials(context.Background(), destinations, nil, t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+
+	destination := destinations[0]
+	args := slices.Concat(destination.orasArgs(), destination.orasTargetArgs(), destination.helmPushArgs())
+	if strings.Contains(strings.Join(args, " "), "hunter2") {
+		t.Errorf("password in command line %v", args)
+	}
+	config, err := os.ReadFile(destination.Auth.ConfigFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(config), `"registry.example.com:5000"`) {
+		t.Errorf("expected the registry address in docker config %s", config)
+	}
+}
@@ -0,0 +1,126 @@
+package vendors
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Result is the outcome of vendoring one version of an entry.
+type Result struct {
+	Name        string        `json:"name"`
+	Kind        string        `json:"kind"`
+	Version     string        `json:"version"`
+	Registry    string        `json:"registry"`
+	Source      string        `json:"source"`
+	Destination string        `json:"destination"`
+	Digest      string        `json:"digest,omitempty"`
+	Size        int64         `json:"size"`
+	Duration    time.Duration `json:"-"`
+	Error       string        `json:"error,omitempty"`
+}
+
+func (r Result) MarshalJSON() ([]byte, error) {
+	type result Result
+	return json.Marshal(struct {
+		result
+		Duration float64 `json:"duration_seconds"`
+	}{result(r), r.Duration.Seconds()})
+}
+
+// Reporter receives the progress of a sync. The results passed to Start and
+// Progress only identify the transfer. Progress is only reported for
+// transfers that toolbox streams itself; other transfers report their size
+// in Finish.
+type Reporter interface {
+	Start(result Result)
+	Progress(result Result, bytes int64)
+	Finish(result Result)
+}
+
+type nopReporter struct{}
+
+func (nopReporter) Start(Result)           {}
+func (nopReporter) Progress(Result, int64) {}
+func (nopReporter) Finish(Result)          {}
+
+// registryRef returns the reference a version is pushed to in registry.
+func registryRef(registry string, entry VendorEntry, version string) string {
+	ref := registry + "/" + RegistryRepository(entry)
+	if strings.HasPrefix(version, "@") {
+		return ref + version
+	}
+	return ref + ":" + RegistryTag(version)
+}
+
+// describeArtifact returns the digest of a pushed manifest and the total size
+// of everything it references, following indexes into their manifests.
+func describeArtifact(ctx context.Context, registry Destination, entry VendorEntry, version string) (string, int64, error) {
+	digest, err := registryDigest(ctx, registry, registryRef(registry.Address, entry, version))
+	if err != nil {
+		return "", 0, err
+	}
+	if digest == "" {
+		return "", 0, fmt.Errorf("%s not found after push", registryRef(registry.Address, entry, version))
+	}
+
+	size, err := manifestSize(ctx, registry, registry.Address+"/"+RegistryRepository(entry), digest)
+	if err != nil {
+		return "", 0, err
+	}
+	return digest, size, nil
+}
+
+func manifestSize(ctx context.Context, registry Destination, repository, digest string) (int64, error) {
+	data, err := commandOutput(ctx, "oras", append([]string{"manifest", "fetch", repository + "@" + digest}, registry.orasArgs()...)...)
+	if err != nil {
+		return 0, err
+	}
+	blobs, children, err := parseManifest(data)
+	if err != nil {
+		return 0, fmt.Errorf("parse manifest %s@%s: %w", repository, digest, err)
+	}
+
+	size := int64(len(data)) + blobs
+	for _, child := range children {
+		childSize, err := manifestSize(ctx, registry, repository, child)
+		if err != nil {
+			return 0, err
+		}
+		size += childSize
+	}
+	return size, nil
+}
+
+// parseManifest returns the total size of the config and layer blobs of a
+// manifest, and the digests of the manifests in an index.
+func parseManifest(data []byte) (int64, []string, error) {
+	type descriptor struct {
+		Digest string `json:"digest"`
+		Size   int64  `json:"size"`
+	}
+	var manifest struct {
+		Config    *descriptor  `json:"config"`
+		Layers    []descriptor `json:"layers"`
+		Blobs     []descriptor `json:"blobs"`
+		Manifests []descriptor `json:"manifests"`
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return 0, nil, err
+	}
+
+	var size int64
+	if manifest.Config != nil {
+		size += manifest.Config.Size
+	}
+	for _, layer := range append(manifest.Layers, manifest.Blobs...) {
+		size += layer.Size
+	}
+	children := make([]string, 0, len(manifest.Manifests))
+	for _, child := range manifest.Manifests {
+		children = append(children, child.Digest)
+	}
+	return size, children, nil
+}
@@ -0,0 +1,51 @@
+package vendors
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseManifest(t *testing.T) {
+	manifest := `{
+		"mediaType": "application/vnd.oci.image.manifest.v1+json",
+		"config": {"digest": "sha256:c0", "size": 100},
+		"layers": [{"digest": "sha256:l1", "size": 1000}, {"digest": "sha256:l2", "size": 2000}]
+	}`
+	size, children, err := parseManifest([]byte(manifest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != 3100 || len(children) != 0 {
+		t.Fatalf("expected size 3100 without children, got %d and %v", size, children)
+	}
+
+	index := `{
+		"mediaType": "application/vnd.oci.image.index.v1+json",
+		"manifests": [{"digest": "sha256:amd64", "size": 500}, {"digest": "sha256:arm64", "size": 500}]
+	}`
+	size, children, err = parseManifest([]byte(index))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"sha256:amd64", "sha256:arm64"}; size != 0 || !slices.Equal(children, want) {
+		t.Fatalf("expected children %v and no blobs, got %v and size %d", want, children, size)
+	}
+}
+
+func TestResultJSON(t *testing.T) {
+	data, err := json.Marshal(Result{Name: "vendor/images/dex", Kind: "image", Version: "v2.43.1", Duration: 1500 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"name":"vendor/images/dex"`, `"duration_seconds":1.5`} {
+		if !strings.Contains(string(data), want) {
+			t.Fatalf("expected %s in %s", want, data)
+		}
+	}
+	if strings.Contains(string(data), `"error"`) {
+		t.Fatalf("expected no error in %s", data)
+	}
+}
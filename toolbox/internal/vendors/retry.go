@@ -0,0 +1,104 @@
+package vendors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+)
+
+const (
+	retryAttempts  = 5
+	retryBaseDelay = time.Second
+	retryMaxDelay  = 30 * time.Second
+)
+
+// transientPatterns match the output of helm, oras, cosign and git for
+// network and registry failures that are worth retrying.
+var transientPatterns = []string{
+	"connection refused",
+	"connection reset",
+	"broken pipe",
+	"i/o timeout",
+	"tls handshake timeout",
+	"timeout awaiting response headers",
+	"unexpected eof",
+	"no such host",
+	"server misbehaving",
+	"too many requests",
+	"500 internal server error",
+	"502 bad gateway",
+	"503 service unavailable",
+	"504 gateway timeout",
+	"the remote end hung up unexpectedly",
+}
+
+// transientError marks an error from an in-process request as retryable,
+// e.g. a 5xx response.
+type transientError struct {
+	err error
+}
+
+func (e transientError) Error() string { return e.err.Error() }
+func (e transientError) Unwrap() error { return e.err }
+
+// statusError returns an error for an unexpected HTTP status, which is
+// transient for server errors and rate limiting.
+func statusError(url string, response *http.Response) error {
+	err := fmt.Errorf("fetch %s: unexpected status %s", url, response.Status)
+	if response.StatusCode >= http.StatusInternalServerError || response.StatusCode == http.StatusTooManyRequests {
+		return transientError{err: err}
+	}
+	return err
+}
+
+// isTransient reports whether err is a network or registry failure, as
+// opposed to a validation failure such as a checksum or signature mismatch
+// that would fail again.
+func isTransient(err error) bool {
+	if errors.As(err, &transientError{}) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	message := strings.ToLower(err.Error())
+	for _, pattern := range transientPatterns {
+		if strings.Contains(message, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// withRetry runs fn until it succeeds, fails with an error that is not
+// transient, or runs out of attempts, doubling the delay between attempts.
+func withRetry(ctx context.Context, operation string, fn func() error) error {
+	delay := retryBaseDelay
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt == retryAttempts || ctx.Err() != nil || !isTransient(err) {
+			return err
+		}
+
+		log.Warnf("%s failed (attempt %d/%d), retrying in %s: %v", operation, attempt, retryAttempts, delay, err)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return err
+		}
+		delay = min(delay*2, retryMaxDelay)
+	}
+}
@@ -0,0 +1,39 @@
+package vendors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestIsTransient(t *testing.T) {
+	cases := []struct {
+		err  error
+		want bool
+	}{
+		{errors.New("oras [cp ...]: exit status 1 (output: Error: Put \"http://127.0.0.1:4242/v2/\": dial tcp 127.0.0.1:4242: connect: connection refused)"), true},
+		{errors.New("helm [push ...]: exit status 1 (output: Error: unexpected status from PUT request: 503 Service Unavailable)"), true},
+		{transientError{err: errors.New("fetch https://example.com: unexpected status 502 Bad Gateway")}, true},
+		{fmt.Errorf("download: %w", errors.New("sha256 mismatch: expected abc, got def")), false},
+		{errors.New("cosign [verify ...]: exit status 1 (output: Error: no matching signatures)"), false},
+		{errors.New("oras [cp ...]: exit status 1 (output: Error: ghcr.io/dexidp/dex:v0.0.0: not found)"), false},
+	}
+
+	for _, tc := range cases {
+		if got := isTransient(tc.err); got != tc.want {
+			t.Errorf("isTransient(%q) = %v, want %v", tc.err, got, tc.want)
+		}
+	}
+}
+
+func TestWithRetryStopsOnPermanentError(t *testing.T) {
+	attempts := 0
+	err := withRetry(context.Background(), "test", func() error {
+		attempts++
+		return errors.New("sha256 mismatch")
+	})
+	if err == nil || attempts != 1 {
+		t.Fatalf("expected one failed attempt, got %d attempts and error %v", attempts, err)
+	}
+}
@@ -0,0 +1,96 @@
+package vendors
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+)
+
+var kinds = []string{"chart", "image", "artifact", "file", "git"}
+
+// Selector narrows the entries a command processes. Each field is a list of
+// alternatives, and an entry must satisfy every non-empty field.
+type Selector struct {
+	// Only lists exact entry names.
+	Only []string
+	// Kinds lists entry kinds.
+	Kinds []string
+	// Match lists glob patterns on entry names, as in path.Match.
+	Match []string
+	// Versions lists versions to keep within the selected entries.
+	Versions []string
+}
+
+func (s Selector) IsEmpty() bool {
+	return len(s.Only) == 0 && len(s.Kinds) == 0 && len(s.Match) == 0 && len(s.Versions) == 0
+}
+
+// FilterEntries returns the entries and versions picked by selector. Every
+// selector must refer to something configured, so that a typo fails instead
+// of silently selecting nothing.
+func FilterEntries(entries []VendorEntry, selector Selector) ([]VendorEntry, error) {
+	if selector.IsEmpty() {
+		return entries, nil
+	}
+
+	for _, name := range selector.Only {
+		if !slices.ContainsFunc(entries, func(entry VendorEntry) bool { return entry.Name == name }) {
+			return nil, fmt.Errorf("vendor entry selector %q is not configured", name)
+		}
+	}
+	for _, kind := range selector.Kinds {
+		if !slices.Contains(kinds, kind) {
+			return nil, fmt.Errorf("invalid vendor kind selector %q (%s)", kind, strings.Join(kinds, "|"))
+		}
+	}
+	for _, pattern := range selector.Match {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid vendor match pattern %q: %w", pattern, err)
+		}
+		if !slices.ContainsFunc(entries, func(entry VendorEntry) bool { return matchesAny(entry.Name, []string{pattern}) }) {
+			return nil, fmt.Errorf("vendor match pattern %q does not match any entry", pattern)
+		}
+	}
+
+	var filtered []VendorEntry
+	for _, entry := range entries {
+		switch {
+		case len(selector.Only) > 0 && !slices.Contains(selector.Only, entry.Name):
+			continue
+		case len(selector.Kinds) > 0 && !slices.Contains(selector.Kinds, entry.Kind):
+			continue
+		case len(selector.Match) > 0 && !matchesAny(entry.Name, selector.Match):
+			continue
+		}
+		if len(selector.Versions) > 0 {
+			versions := slices.DeleteFunc(slices.Clone(entry.Versions), func(version string) bool {
+				return !slices.Contains(selector.Versions, version)
+			})
+			if len(versions) == 0 {
+				continue
+			}
+			entry.Versions = versions
+		}
+		filtered = append(filtered, entry)
+	}
+
+	for _, version := range selector.Versions {
+		if !slices.ContainsFunc(filtered, func(entry VendorEntry) bool { return slices.Contains(entry.Versions, version) }) {
+			return nil, fmt.Errorf("vendor version selector %q is not configured for the selected entries", version)
+		}
+	}
+	if len(filtered) == 0 {
+		return nil, fmt.Errorf("no vendor entries match the selectors")
+	}
+	return filtered, nil
+}
+
+func matchesAny(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,59 @@
+package vendors
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFilterEntries(t *testing.T) {
+	entries := []VendorEntry{
+		{Name: "vendor/charts/dex", Vendor: Vendor{Kind: "chart", Versions: []string{"0.23.0", "0.24.0"}}},
+		{Name: "vendor/charts/vault", Vendor: Vendor{Kind: "chart", Versions: []string{"0.30.0"}}},
+		{Name: "vendor/images/dexidp/dex", Vendor: Vendor{Kind: "image", Versions: []string{"v2.43.1"}}},
+	}
+
+	cases := []struct {
+		name     string
+		selector Selector
+		want     []string
+		wantErr  string
+	}{
+		{"no selectors", Selector{}, []string{"vendor/charts/dex@0.23.0,0.24.0", "vendor/charts/vault@0.30.0", "vendor/images/dexidp/dex@v2.43.1"}, ""},
+		{"only", Selector{Only: []string{"vendor/charts/vault"}}, []string{"vendor/charts/vault@0.30.0"}, ""},
+		{"kind and match", Selector{Kinds: []string{"chart"}, Match: []string{"*/*/dex*"}}, []string{"vendor/charts/dex@0.23.0,0.24.0"}, ""},
+		{"version", Selector{Only: []string{"vendor/charts/dex"}, Versions: []string{"0.24.0"}}, []string{"vendor/charts/dex@0.24.0"}, ""},
+		{"unknown entry", Selector{Only: []string{"vendor/charts/dexx"}}, nil, "is not configured"},
+		{"invalid kind", Selector{Kinds: []string{"helm"}}, nil, "invalid vendor kind selector"},
+		{"pattern without match", Selector{Match: []string{"vendor/files/*"}}, nil, "does not match any entry"},
+		{"invalid pattern", Selector{Match: []string{"vendor/[charts"}}, nil, "invalid vendor match pattern"},
+		{"version of another entry", Selector{Only: []string{"vendor/charts/vault"}, Versions: []string{"0.24.0"}}, nil, "not configured for the selected entries"},
+		{"disjoint selectors", Selector{Only: []string{"vendor/charts/vault"}, Kinds: []string{"image"}}, nil, "no vendor entries match"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			filtered, err := FilterEntries(entries, tc.selector)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("expected error %q, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, entry := range filtered {
+				got = append(got, entry.Name+"@"+strings.Join(entry.Versions, ","))
+			}
+			if strings.Join(got, " ") != strings.Join(tc.want, " ") {
+				t.Fatalf("expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+
+	if len(entries[0].Versions) != 2 {
+		t.Fatalf("FilterEntries modified the input versions: %v", entries[0].Versions)
+	}
+}
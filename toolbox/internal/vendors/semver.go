@@ -0,0 +1,89 @@
+package vendors
+
+import (
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var versionPattern = regexp.MustCompile(`^(v?)(\d+(?:\.\d+){0,3})([-+].*)?$`)
+
+// version is a loosely semver-shaped tag. Only tags with the same prefix,
+// number of components and suffix are comparable, so 17.6-system-trixie is
+// compared with 17.7-system-trixie but never with 18.0 or 17.7-rc.1.
+type version struct {
+	raw    string
+	prefix string
+	parts  []int
+	suffix string
+}
+
+func parseVersion(raw string) (version, bool) {
+	match := versionPattern.FindStringSubmatch(raw)
+	if match == nil {
+		return version{}, false
+	}
+
+	fields := strings.Split(match[2], ".")
+	parts := make([]int, len(fields))
+	for i, field := range fields {
+		part, err := strconv.Atoi(field)
+		if err != nil {
+			return version{}, false
+		}
+		parts[i] = part
+	}
+	return version{raw: raw, prefix: match[1], parts: parts, suffix: match[3]}, true
+}
+
+func (v version) comparable(other version) bool {
+	return v.prefix == other.prefix && v.suffix == other.suffix && len(v.parts) == len(other.parts)
+}
+
+func (v version) compare(other version) int { return slices.Compare(v.parts, other.parts) }
+
+type latestVersions struct {
+	Patch string
+	Minor string
+	Major string
+}
+
+// newerVersions returns the newest candidate that only changes the last
+// component (patch), keeps the first component (minor), or is unrestricted
+// (major). Levels without a newer candidate are left empty.
+func newerVersions(current string, candidates []string) (latestVersions, bool) {
+	base, ok := parseVersion(current)
+	if !ok {
+		return latestVersions{}, false
+	}
+
+	var patch, minor, major *version
+	for _, candidate := range candidates {
+		v, ok := parseVersion(candidate)
+		if !ok || !base.comparable(v) || v.compare(base) <= 0 {
+			continue
+		}
+		if major == nil || v.compare(*major) > 0 {
+			major = &v
+		}
+		if v.parts[0] == base.parts[0] && (minor == nil || v.compare(*minor) > 0) {
+			minor = &v
+		}
+		if slices.Equal(v.parts[:len(v.parts)-1], base.parts[:len(base.parts)-1]) && (patch == nil || v.compare(*patch) > 0) {
+			patch = &v
+		}
+	}
+
+	var latest latestVersions
+	if patch != nil {
+		latest.Patch = patch.raw
+	}
+	if minor != nil {
+		latest.Minor = minor.raw
+	}
+	if major != nil {
+		latest.Major = major.raw
+	}
+	return latest, true
+}
@@ -0,0 +1,35 @@
+package vendors
+
+import "testing"
+
+func TestNewerVersions(t *testing.T) {
+	cases := []struct {
+		name       string
+		current    string
+		candidates []string
+		want       latestVersions
+	}{
+		{"semver levels", "0.23.0", []string{"0.22.0", "0.23.1", "0.23.2", "0.24.0", "1.0.0", "1.1.0-rc.1"}, latestVersions{Patch: "0.23.2", Minor: "0.24.0", Major: "1.0.0"}},
+		{"v prefix", "v2.43.1", []string{"2.44.0", "v2.43.2", "v2.44.0", "latest"}, latestVersions{Patch: "v2.43.2", Minor: "v2.44.0", Major: "v2.44.0"}},
+		{"suffix", "17.6-system-trixie", []string{"17.7", "17.7-system-bookworm", "17.7-system-trixie", "18.0-system-trixie"}, latestVersions{Patch: "17.7-system-trixie", Minor: "17.7-system-trixie", Major: "18.0-system-trixie"}},
+		{"up to date", "1.29.0", []string{"1.28.0", "1.29.0"}, latestVersions{}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := newerVersions(tc.current, tc.candidates)
+			if !ok {
+				t.Fatalf("expected %q to be comparable", tc.current)
+			}
+			if got != tc.want {
+				t.Fatalf("expected %+v, got %+v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestNewerVersionsSkipsDigests(t *testing.T) {
+	if _, ok := newerVersions("@sha256:abc", []string{"1.0.0"}); ok {
+		t.Fatal("expected digest to be skipped")
+	}
+}
@@ -1,18 +1,21 @@
 package vendors
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/log"
 )
 
-func LoadVendors(configPath string) ([]VendorEntry, error) {
-	config, err := LoadConfig(configPath)
+func LoadVendors(configPath, env string) ([]VendorEntry, error) {
+	config, err := LoadConfig(configPath, env)
 	if err != nil {
 		return nil, fmt.Errorf("load settings file: %w", err)
 	}
@@ -25,85 +28,319 @@ func LoadVendors(configPath string) ([]VendorEntry, error) {
 	return entries, nil
 }
 
-func Sync(ctx context.Context, workdir, registryAddr string, entries []VendorEntry) error {
+type SyncOptions struct {
+	Workdir string
+	// Destinations are the registries every entry except git is pushed to.
+	Destinations []Destination
+	// Git is where git entries are mirrored to, and is only required when
+	// there are git entries.
+	Git *GitRemote
+	// Reporter receives the progress of every entry version when set.
+	Reporter Reporter
+}
+
+// Sync vendors every version of every entry into every destination and stops
+// at the first failure. The returned results include the version that failed.
+func Sync(ctx context.Context, options SyncOptions, entries []VendorEntry) ([]Result, error) {
+	reporter := options.Reporter
+	if reporter == nil {
+		reporter = nopReporter{}
+	}
+
+	var results []Result
 	for _, item := range entries {
-		switch item.Kind {
-		case "chart":
-			if err := syncChart(ctx, workdir, registryAddr, item); err != nil {
-				return err
-			}
-		case "image":
-			if err := syncImage(ctx, registryAddr, item); err != nil {
-				return err
+		destinations := options.Destinations
+		if item.Kind == "git" {
+			// Git entries are mirrored into Forgejo instead of a registry.
+			destinations = []Destination{{Name: "forgejo"}}
+		}
+
+		for _, version := range item.Versions {
+			for _, destination := range destinations {
+				log.Infof("vendoring %s %s@%s to %s", item.Kind, item.Name, version, destination.Name)
+
+				result := Result{Name: item.Name, Kind: item.Kind, Version: version, Registry: destination.Name}
+				reporter.Start(result)
+				progress := func(bytes int64) { reporter.Progress(result, bytes) }
+
+				started := time.Now()
+				// Retries are cheap after a partial transfer: helm and oras
+				// check which blobs the registry has and only upload the rest.
+				err := withRetry(ctx, fmt.Sprintf("vendor %s@%s to %s", item.Name, version, destination.Name), func() error {
+					return syncVersion(ctx, options, destination, item, version, &result, progress)
+				})
+				result.Duration = time.Since(started)
+				if err != nil {
+					result.Error = err.Error()
+				}
+
+				reporter.Finish(result)
+				results = append(results, result)
+				if err != nil {
+					return results, err
+				}
 			}
 		}
 	}
+	return results, nil
+}
+
+func syncVersion(ctx context.Context, options SyncOptions, destination Destination, item VendorEntry, version string, result *Result, progress func(int64)) error {
+	var err error
+	switch item.Kind {
+	case "chart":
+		err = syncChart(ctx, options.Workdir, destination, item, version, result)
+	case "image":
+		err = syncImage(ctx, destination, item, version, result)
+	case "artifact":
+		err = syncArtifact(ctx, destination, item, version, result)
+	case "file":
+		err = syncFile(ctx, options.Workdir, destination, item, version, result, progress)
+	case "git":
+		return syncGit(ctx, options.Workdir, options.Git, item, version, result)
+	}
+	if err != nil {
+		return err
+	}
+
+	result.Destination = registryRef(destination.PullAddress, item, version)
+	if result.Digest, result.Size, err = describeArtifact(ctx, destination, item, version); err != nil {
+		return fmt.Errorf("inspect %s@%s: %w", item.Name, version, err)
+	}
 	return nil
 }
 
-func syncChart(ctx context.Context, workdir, registryAddr string, chart VendorEntry) error {
-	chartDir := filepath.Join(workdir, chart.Name)
-	if err := os.MkdirAll(chartDir, 0o755); err != nil {
-		return fmt.Errorf("create chart temp dir: %w", err)
+func syncChart(ctx context.Context, workdir string, destination Destination, chart VendorEntry, version string, result *Result) error {
+	result.Source = chart.pullRef() + ":" + version
+	if chart.RepoURL != "" {
+		result.Source = strings.TrimSuffix(chart.RepoURL, "/") + "/" + result.Source
+	}
+
+	// Verified charts are pulled, and so verified, even when the version was
+	// already pushed, so a chart that no longer verifies fails the sync.
+	var archivePath string
+	if chart.Verify != nil {
+		var err error
+		if archivePath, err = pullChart(ctx, workdir, chart, version); err != nil {
+			return err
+		}
 	}
 
-	pullRef := chart.Chart
-	if chart.Ref != "" {
-		pullRef = chart.Ref
+	// Chart versions are immutable, so a pushed version is never pushed again,
+	// unless it was pushed without the provenance file verification requires.
+	if pushed, err := chartPushed(ctx, destination, registryRef(destination.Address, chart, version), chart.Verify != nil); err != nil || pushed {
+		return err
 	}
-	for _, version := range chart.Versions {
-		log.Infof("vendoring chart %s@%s", chart.Name, version)
 
-		pullArgs := []string{"pull", pullRef, "--version", version, "--destination", chartDir}
-		if chart.RepoURL != "" {
-			pullArgs = append(pullArgs, "--repo", chart.RepoURL)
+	if archivePath == "" {
+		var err error
+		if archivePath, err = pullChart(ctx, workdir, chart, version); err != nil {
+			return err
 		}
-		if err := runCommand(ctx, "helm", pullArgs...); err != nil {
-			return fmt.Errorf("pull chart %s@%s: %w", chart.Name, version, err)
+	}
+
+	pushTarget := fmt.Sprintf("oci://%s/%s", destination.Address, chart.Name)
+	pushArgs := append([]string{"push", archivePath, pushTarget}, destination.helmPushArgs()...)
+	if err := runCommand(ctx, "helm", pushArgs...); err != nil {
+		return fmt.Errorf("push chart %s@%s: %w", chart.Name, version, err)
+	}
+
+	return nil
+}
+
+func pullChart(ctx context.Context, workdir string, chart VendorEntry, version string) (string, error) {
+	chartDir := filepath.Join(workdir, chart.Name, version)
+	if err := os.MkdirAll(chartDir, 0o755); err != nil {
+		return "", fmt.Errorf("create chart temp dir: %w", err)
+	}
+
+	archivePath := filepath.Join(chartDir, filepath.Base(chart.pullRef())+"-"+version+".tgz")
+	// The chart is pulled once and pushed to every destination.
+	if _, err := os.Stat(archivePath); err == nil {
+		return archivePath, nil
+	}
+
+	ref, chartArgs, err := chart.helmChartArgs(ctx)
+	if err != nil {
+		return "", err
+	}
+	pullArgs := append([]string{"pull", ref, "--version", version, "--destination", chartDir}, chartArgs...)
+	// helm pull --verify also downloads the .prov file, which helm push
+	// then uploads alongside the chart.
+	if chart.Verify != nil {
+		pullArgs = append(pullArgs, "--verify", "--keyring", chart.Verify.Keyring)
+	}
+	if err := runCommand(ctx, "helm", pullArgs...); err != nil {
+		return "", fmt.Errorf("pull chart %s@%s: %w", chart.Name, version, err)
+	}
+
+	return archivePath, nil
+}
+
+func syncImage(ctx context.Context, registry Destination, image VendorEntry, version string, result *Result) error {
+	source, target := imageRefs(image, version)
+	result.Source = source
+	destination := registry.Address + "/" + target
+	copyArgs := []string{"cp", source, destination}
+
+	var digest string
+	if image.Verify != nil {
+		var err error
+		if digest, err = verifyImage(ctx, image, source); err != nil {
+			return fmt.Errorf("verify image %s@%s: %w", image.Name, version, err)
 		}
+		// Copy the verified digest and its referrers, which include
+		// signatures stored with the OCI referrers API.
+		copyArgs = []string{"cp", "--recursive", image.Source + "@" + digest, destination}
+	}
+
+	if present, err := alreadyCopied(ctx, registry, image, source, digest, destination); err != nil || present {
+		return err
+	}
 
-		archivePath := filepath.Join(chartDir, filepath.Base(pullRef)+"-"+version+".tgz")
-		pushTarget := fmt.Sprintf("oci://%s/%s", registryAddr, chart.Name)
-		if err := runCommand(ctx, "helm", "push", archivePath, pushTarget, "--plain-http"); err != nil {
-			return fmt.Errorf("push chart %s@%s: %w", chart.Name, version, err)
+	copyArgs = slices.Concat(copyArgs, image.Auth.orasSourceArgs(), registry.orasTargetArgs())
+	if err := runCommand(ctx, "oras", copyArgs...); err != nil {
+		return fmt.Errorf("copy image %s@%s: %w", image.Name, version, err)
+	}
+
+	if digest != "" {
+		if err := copySignatureTags(ctx, image, digest, registry.Address+"/"+image.Name, registry.orasTargetArgs()...); err != nil {
+			return fmt.Errorf("copy signatures for %s@%s: %w", image.Name, version, err)
 		}
 	}
 
 	return nil
 }
 
-func syncImage(ctx context.Context, registryAddr string, image VendorEntry) error {
-	for _, version := range image.Versions {
-		log.Infof("vendoring image %s:%s", image.Name, version)
+func syncArtifact(ctx context.Context, registry Destination, artifact VendorEntry, version string, result *Result) error {
+	source, target := imageRefs(artifact, version)
+	result.Source = source
+	destination := registry.Address + "/" + target
+	if present, err := alreadyCopied(ctx, registry, artifact, source, "", destination); err != nil || present {
+		return err
+	}
+
+	copyArgs := []string{"cp", "--recursive", source, destination}
+	copyArgs = slices.Concat(copyArgs, artifact.Auth.orasSourceArgs(), registry.orasTargetArgs())
+	if err := runCommand(ctx, "oras", copyArgs...); err != nil {
+		return fmt.Errorf("copy artifact %s@%s: %w", artifact.Name, version, err)
+	}
+
+	return nil
+}
 
-		source := image.Source
-		target := image.Name
-		if strings.HasPrefix(version, "@") {
-			source += version
-			target += version
-		} else {
-			source += ":" + version
-			target += ":" + version
+// alreadyCopied reports whether destination already points at the upstream
+// manifest, resolving source when its digest is not known yet.
+func alreadyCopied(ctx context.Context, registry Destination, entry VendorEntry, source, digest, destination string) (bool, error) {
+	existing, err := registryDigest(ctx, registry, destination)
+	if err != nil || existing == "" {
+		return false, err
+	}
+	if digest == "" {
+		output, err := commandOutput(ctx, "oras", append([]string{"resolve", source}, entry.Auth.orasArgs()...)...)
+		if err != nil {
+			return false, fmt.Errorf("resolve %s: %w", source, err)
 		}
-		destination := fmt.Sprintf("%s/%s", registryAddr, target)
-		copyArgs := []string{"cp", source, destination, "--to-plain-http"}
+		digest = strings.TrimSpace(string(output))
+	}
+	return existing == digest, nil
+}
 
-		if err := runCommand(ctx, "oras", copyArgs...); err != nil {
-			return fmt.Errorf("copy image %s@%s: %w", image.Name, version, err)
+// registryDigest returns the digest ref points at in the destination
+// registry, or an empty string when it does not exist.
+func registryDigest(ctx context.Context, registry Destination, ref string) (string, error) {
+	output, err := commandOutput(ctx, "oras", append([]string{"resolve", ref}, registry.orasArgs()...)...)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return "", nil
 		}
+		return "", fmt.Errorf("resolve %s: %w", ref, err)
 	}
+	return strings.TrimSpace(string(output)), nil
+}
 
-	return nil
+// imageRefs returns the upstream reference and the destination repository
+// with the tag or digest of version.
+func imageRefs(image VendorEntry, version string) (string, string) {
+	if strings.HasPrefix(version, "@") {
+		return image.Source + version, image.Name + version
+	}
+	return image.Source + ":" + version, image.Name + ":" + version
+}
+
+// helmChartArgs returns the chart reference and flags that helm pull and
+// template need for the chart. Charts from HTTP repositories with credentials
+// go through a repositories file, since helm only takes their password as a
+// flag otherwise.
+func (v VendorEntry) helmChartArgs(ctx context.Context) (string, []string, error) {
+	switch {
+	case v.Auth != nil && v.Auth.RepositoryConfig != "":
+		args := []string{"--repository-config", v.Auth.RepositoryConfig, "--repository-cache", filepath.Join(filepath.Dir(v.Auth.RepositoryConfig), "cache")}
+		if err := runCommand(ctx, "helm", append([]string{"repo", "update", helmRepositoryName}, args...)...); err != nil {
+			return "", nil, fmt.Errorf("update chart repository %s: %w", v.RepoURL, err)
+		}
+		return helmRepositoryName + "/" + v.Chart, args, nil
+	case v.RepoURL != "":
+		return v.Chart, []string{"--repo", v.RepoURL}, nil
+	default:
+		return v.pullRef(), v.Auth.helmArgs(), nil
+	}
+}
+
+func (v VendorEntry) pullRef() string {
+	if v.Ref != "" {
+		return v.Ref
+	}
+	return v.Chart
 }
 
 func runCommand(ctx context.Context, name string, args ...string) error {
+	return runCommandIn(ctx, "", name, args...)
+}
+
+// runCommandIn runs a command in dir and captures its output, which is only
+// shown with debug logging or when the command fails.
+func runCommandIn(ctx context.Context, dir, name string, args ...string) error {
+	var output bytes.Buffer
 	cmd := exec.CommandContext(ctx, name, args...)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	cmd.Dir = dir
+	cmd.Stdout = &output
+	cmd.Stderr = &output
 
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("%s %v: %w", name, args, err)
+	err := cmd.Run()
+	log.Debug("command output", "command", name, "output", strings.TrimSpace(output.String()))
+	if err != nil {
+		return fmt.Errorf("%s: %w (output: %s)", commandName(name, args), err, strings.TrimSpace(output.String()))
 	}
 
 	return nil
 }
+
+func commandOutput(ctx context.Context, name string, args ...string) ([]byte, error) {
+	return commandOutputEnv(ctx, nil, name, args...)
+}
+
+func commandOutputEnv(ctx context.Context, env []string, name string, args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stderr = &stderr
+	if env != nil {
+		cmd.Env = append(os.Environ(), env...)
+	}
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w (output: %s)", commandName(name, args), err, strings.TrimSpace(stderr.String()))
+	}
+
+	return output, nil
+}
+
+// commandName names a command in errors by its subcommand. Errors end up in
+// logs and reports, so they never include the full arguments.
+func commandName(name string, args []string) string {
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		return name + " " + args[0]
+	}
+	return name
+}
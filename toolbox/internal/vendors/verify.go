@@ -0,0 +1,119 @@
+package vendors
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/log"
+)
+
+// cosignTagSuffixes are the legacy cosign tags stored next to the image, as
+// opposed to signatures attached through the OCI referrers API.
+var cosignTagSuffixes = []string{".sig", ".att"}
+
+// cosignTagDigest returns the digest of the image that a legacy cosign
+// signature, attestation or SBOM tag belongs to.
+func cosignTagDigest(tag string) (string, bool) {
+	encoded, ok := strings.CutPrefix(tag, "sha256-")
+	if !ok {
+		return "", false
+	}
+	for _, suffix := range []string{".sig", ".att", ".sbom"} {
+		if encoded, ok := strings.CutSuffix(encoded, suffix); ok {
+			return "sha256:" + encoded, true
+		}
+	}
+	return "", false
+}
+
+func (v VendorEntry) NeedsCosign() bool {
+	return v.Kind == "image" && v.Verify != nil
+}
+
+// verifyImage resolves source to a digest and verifies its cosign signature,
+// so the copy that follows pushes exactly the manifest that was verified.
+func verifyImage(ctx context.Context, image VendorEntry, source string) (string, error) {
+	output, err := commandOutput(ctx, "oras", append([]string{"resolve", source}, image.Auth.orasArgs()...)...)
+	if err != nil {
+		return "", fmt.Errorf("resolve %s: %w", source, err)
+	}
+	digest := strings.TrimSpace(string(output))
+
+	args := []string{"verify", image.Source + "@" + digest}
+	verify := image.Verify
+	switch {
+	case verify.Key != "":
+		args = append(args, "--key", verify.Key)
+	default:
+		if verify.Identity != "" {
+			args = append(args, "--certificate-identity", verify.Identity)
+		} else {
+			args = append(args, "--certificate-identity-regexp", verify.IdentityRegexp)
+		}
+		if verify.Issuer != "" {
+			args = append(args, "--certificate-oidc-issuer", verify.Issuer)
+		} else {
+			args = append(args, "--certificate-oidc-issuer-regexp", verify.IssuerRegexp)
+		}
+	}
+
+	if _, err := commandOutputEnv(ctx, image.Auth.cosignEnv(), "cosign", args...); err != nil {
+		return "", fmt.Errorf("verify signature of %s: %w", source, err)
+	}
+	log.Infof("verified signature of %s@%s", image.Source, digest)
+	return digest, nil
+}
+
+// copySignatureTags copies legacy cosign signature and attestation tags for
+// digest next to the copied image, so admission controllers in the cluster can
+// verify the copy again.
+func copySignatureTags(ctx context.Context, image VendorEntry, digest, destination string, destinationArgs ...string) error {
+	for _, suffix := range cosignTagSuffixes {
+		tag := strings.Replace(digest, ":", "-", 1) + suffix
+		source := image.Source + ":" + tag
+		if _, err := commandOutput(ctx, "oras", append([]string{"resolve", source}, image.Auth.orasArgs()...)...); err != nil {
+			log.Debugf("no %s tag for %s", suffix, source)
+			continue
+		}
+		copyArgs := slices.Concat([]string{"cp", source, destination + ":" + tag}, destinationArgs, image.Auth.orasSourceArgs())
+		if err := runCommand(ctx, "oras", copyArgs...); err != nil {
+			return fmt.Errorf("copy %s: %w", source, err)
+		}
+	}
+	return nil
+}
+
+// chartPushed reports whether ref exists in the destination registry and,
+// when withProvenance is set, was pushed with its provenance file.
+func chartPushed(ctx context.Context, registry Destination, ref string, withProvenance bool) (bool, error) {
+	digest, err := registryDigest(ctx, registry, ref)
+	if err != nil || digest == "" || !withProvenance {
+		return digest != "", err
+	}
+	data, err := commandOutput(ctx, "oras", append([]string{"manifest", "fetch", ref}, registry.orasArgs()...)...)
+	if err != nil {
+		return false, fmt.Errorf("fetch manifest %s: %w", ref, err)
+	}
+	return hasProvenance(data)
+}
+
+// hasProvenance reports whether a chart manifest has a provenance layer.
+func hasProvenance(data []byte) (bool, error) {
+	var manifest struct {
+		Layers []struct {
+			MediaType string `json:"mediaType"`
+		} `json:"layers"`
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return false, fmt.Errorf("parse chart manifest: %w", err)
+	}
+	for _, layer := range manifest.Layers {
+		if layer.MediaType == helmProvenanceMediaType {
+			return true, nil
+		}
+	}
+	return false, nil
+}
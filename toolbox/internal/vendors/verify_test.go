@@ -0,0 +1,29 @@
+package vendors
+
+import "testing"
+
+func TestHasProvenance(t *testing.T) {
+	cases := []struct {
+		name     string
+		manifest string
+		want     bool
+	}{
+		{"chart with provenance", `{"layers": [
+			{"mediaType": "application/vnd.cncf.helm.chart.content.v1.tar+gzip"},
+			{"mediaType": "application/vnd.cncf.helm.chart.provenance.v1.prov"}
+		]}`, true},
+		{"chart without provenance", `{"layers": [{"mediaType": "application/vnd.cncf.helm.chart.content.v1.tar+gzip"}]}`, false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := hasProvenance([]byte(tc.manifest))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Errorf("expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+}